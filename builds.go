@@ -31,19 +31,22 @@ import (
 
 // Build is a stored player build (stats + talents).
 type Build struct {
-	BuildID     int64         `json:"buildId"`
-	BuildName   string        `json:"buildName"`
-	Description *string       `json:"description,omitempty"`
-	Strength    int           `json:"strength"`
-	Stamina     int           `json:"stamina"`
-	Agility     int           `json:"agility"`
-	Luck        int           `json:"luck"`
-	Armor       int           `json:"armor"`
-	MinDamage   int           `json:"minDamage"`
-	MaxDamage   int           `json:"maxDamage"`
-	CreatedAt   time.Time     `json:"createdAt"`
-	UpdatedAt   time.Time     `json:"updatedAt"`
-	Talents     []BuildTalent `json:"talents"`
+	BuildID     int64          `json:"buildId"`
+	BuildName   string         `json:"buildName"`
+	Description *string        `json:"description,omitempty"`
+	Strength    int            `json:"strength"`
+	Stamina     int            `json:"stamina"`
+	Agility     int            `json:"agility"`
+	Luck        int            `json:"luck"`
+	Armor       int            `json:"armor"`
+	MinDamage   int            `json:"minDamage"`
+	MaxDamage   int            `json:"maxDamage"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	Talents     []BuildTalent  `json:"talents"`
+	Equipment   []EquippedItem `json:"equipment,omitempty"`
+	Blessings   []int          `json:"blessings,omitempty"` // blessing perk IDs
+	Potions     []CombatPotion `json:"potions,omitempty"`
 }
 
 // BuildTalent mirrors EnemyTalent (talent_id + points + order + optional perk).
@@ -61,6 +64,9 @@ type BuildRunConfig struct {
 	FightsPerPair int              `json:"fightsPerPair"` // fights per pairing
 	BuildIDs      []int64          `json:"buildIds"`      // builds participating
 	BuildNames    map[int64]string `json:"buildNames"`
+	Seed          int64            `json:"seed"` // master seed; every fight seed is drawn from it
+	RulesID       *int64           `json:"rulesProfileId,omitempty"`
+	Rules         *CombatRules     `json:"rules,omitempty"` // snapshot taken at start so later profile edits don't change the run
 	StartedAt     time.Time        `json:"startedAt"`
 }
 
@@ -69,7 +75,9 @@ type StartBuildRunRequest struct {
 	Milestones    []int   `json:"milestones,omitempty"`
 	Rounds        int     `json:"rounds,omitempty"`
 	FightsPerPair int     `json:"fightsPerPair,omitempty"`
-	BuildIDs      []int64 `json:"buildIds,omitempty"` // empty = all
+	BuildIDs      []int64 `json:"buildIds,omitempty"`       // empty = all
+	Seed          *int64  `json:"seed,omitempty"`           // nil = random; pass a stored seed to reproduce a run
+	RulesID       *int64  `json:"rulesProfileId,omitempty"` // nil = default rules
 }
 
 // BuildResultRow is one (build, milestone) result.
@@ -81,6 +89,8 @@ type BuildResultRow struct {
 	Wins         int     `json:"wins"`
 	Losses       int     `json:"losses"`
 	Rank         int     `json:"rank"`
+
+	Metrics *CombatMetricsSummary `json:"metrics,omitempty"` // time to kill and damage by source; nil for builds added to a finished run
 }
 
 // BuildRun is the full run for the rankings UI.
@@ -124,6 +134,7 @@ func scaleStat(base, day int) int {
 // snapshotBuild produces the CombatCharacter the build represents on `day`.
 // Talents are consumed in talent_order, one point per day. A perk attached
 // to a perk-slot talent activates the day that talent reaches its maxPoints.
+// Equipment, blessings and potions are there from day 0 and don't scale.
 func snapshotBuild(
 	charID int,
 	b *Build,
@@ -131,6 +142,7 @@ func snapshotBuild(
 	talents map[int]TalentInfo,
 	effects map[int]Effect,
 	perks map[int]Perk,
+	items map[int]Item,
 ) *CombatCharacter {
 
 	c := &CombatCharacter{
@@ -147,17 +159,37 @@ func snapshotBuild(
 	if c.Stamina < 1 {
 		c.Stamina = 1
 	}
+	// Day 1 = first point spent.
+	c.Effects = talentEffects(b.Talents, day, talents, effects, perks)
+	equipCharacter(c, b.Equipment, items, effects)
+	blessCharacter(c, b.Blessings, perks, effects)
+	stockPotions(c, b.Potions, items, effects)
+	return c
+}
+
+// talentEffects resolves talents into combat effects, spending up to
+// `points` talent points in talent_order. A talent's own effect scales with
+// the points spent on it; a perk attached to a perk-slot talent counts only
+// once that talent reaches its maxPoints. Effects are numbered from 1.
+func talentEffects(
+	owned []BuildTalent,
+	points int,
+	talents map[int]TalentInfo,
+	effects map[int]Effect,
+	perks map[int]Perk,
+) []CombatTestEffect {
 
 	// Sort talents by talent_order so the daily sequence is deterministic.
-	ordered := make([]BuildTalent, len(b.Talents))
-	copy(ordered, b.Talents)
+	ordered := make([]BuildTalent, len(owned))
+	copy(ordered, owned)
 	sort.Slice(ordered, func(i, j int) bool {
 		return ordered[i].TalentOrder < ordered[j].TalentOrder
 	})
 
-	// Day 1 = first point spent. Cap by total available points.
-	pointsAvailable := day
+	// Cap by total available points.
+	pointsAvailable := points
 	effectIdSeq := 1
+	var out []CombatTestEffect
 
 	for _, bt := range ordered {
 		if pointsAvailable <= 0 {
@@ -177,7 +209,7 @@ func snapshotBuild(
 		// Talent's own effect (passive scaling): value = factor × spent.
 		if t.EffectID != nil && t.Factor != nil {
 			if e, ok := effects[*t.EffectID]; ok {
-				c.Effects = append(c.Effects, effectFromTemplate(effectIdSeq, e, *t.Factor*spent))
+				out = append(out, effectFromTemplate(effectIdSeq, e, *t.Factor*spent))
 				effectIdSeq++
 			}
 		}
@@ -188,25 +220,24 @@ func snapshotBuild(
 			if p, ok := perks[*bt.PerkID]; ok {
 				if p.Effect1ID != nil && p.Factor1 != nil {
 					if e, ok := effects[*p.Effect1ID]; ok {
-						c.Effects = append(c.Effects, effectFromTemplate(effectIdSeq, e, *p.Factor1))
+						out = append(out, effectFromTemplate(effectIdSeq, e, *p.Factor1))
 						effectIdSeq++
 					}
 				}
 				if p.Effect2ID != nil && p.Factor2 != nil {
 					if e, ok := effects[*p.Effect2ID]; ok {
-						c.Effects = append(c.Effects, effectFromTemplate(effectIdSeq, e, *p.Factor2))
+						out = append(out, effectFromTemplate(effectIdSeq, e, *p.Factor2))
 						effectIdSeq++
 					}
 				}
 			}
 		}
 	}
-
-	return c
+	return out
 }
 
 // effectFromTemplate clones a DB Effect into a CombatTestEffect with the
-// computed value. Mirrors `tool/combat-tester.js#resolveTalentEffects`.
+// computed value.
 func effectFromTemplate(id int, e Effect, value int) CombatTestEffect {
 	core := ""
 	if e.CoreEffectCode != nil {
@@ -224,8 +255,9 @@ func effectFromTemplate(id int, e Effect, value int) CombatTestEffect {
 	if e.TargetSelf != nil {
 		targetSelf = *e.TargetSelf
 	}
-	return CombatTestEffect{
+	eff := CombatTestEffect{
 		EffectID:       id,
+		SourceEffectID: e.ID,
 		CoreEffectCode: core,
 		TriggerType:    trigger,
 		FactorType:     factorType,
@@ -234,24 +266,52 @@ func effectFromTemplate(id int, e Effect, value int) CombatTestEffect {
 		ConditionValue: e.ConditionValue,
 		Duration:       e.Duration,
 		Value:          value,
+		IgnoreArmor:    e.DotIgnoreArmor,
+		Condition:      e.Condition,
+		ProcChance:     e.ProcChance,
+	}
+	if e.DotStacking != nil {
+		eff.Stacking = *e.DotStacking
+	}
+	if e.DotMaxStacks != nil {
+		eff.MaxStacks = *e.DotMaxStacks
+	}
+	if e.DotDecay != nil {
+		eff.Decay = *e.DotDecay
+	}
+	if e.AoE != nil {
+		eff.AoE = *e.AoE
+	}
+	if e.Cooldown != nil {
+		eff.Cooldown = *e.Cooldown
 	}
+	if e.MaxProcs != nil {
+		eff.MaxProcs = *e.MaxProcs
+	}
+	if e.RemoveOrder != nil {
+		eff.RemoveOrder = *e.RemoveOrder
+	}
+	return eff
 }
 
 // ── CRUD: builds ────────────────────────────────────────────────────────────
 
 // SaveBuildRequest is the body for POST /api/saveBuild.
 type SaveBuildRequest struct {
-	BuildID     *int64        `json:"buildId,omitempty"` // nil = create
-	BuildName   string        `json:"buildName"`
-	Description *string       `json:"description,omitempty"`
-	Strength    int           `json:"strength"`
-	Stamina     int           `json:"stamina"`
-	Agility     int           `json:"agility"`
-	Luck        int           `json:"luck"`
-	Armor       int           `json:"armor"`
-	MinDamage   int           `json:"minDamage"`
-	MaxDamage   int           `json:"maxDamage"`
-	Talents     []BuildTalent `json:"talents"`
+	BuildID     *int64         `json:"buildId,omitempty"` // nil = create
+	BuildName   string         `json:"buildName"`
+	Description *string        `json:"description,omitempty"`
+	Strength    int            `json:"strength"`
+	Stamina     int            `json:"stamina"`
+	Agility     int            `json:"agility"`
+	Luck        int            `json:"luck"`
+	Armor       int            `json:"armor"`
+	MinDamage   int            `json:"minDamage"`
+	MaxDamage   int            `json:"maxDamage"`
+	Talents     []BuildTalent  `json:"talents"`
+	Equipment   []EquippedItem `json:"equipment,omitempty"`
+	Blessings   []int          `json:"blessings,omitempty"` // blessing perk IDs
+	Potions     []CombatPotion `json:"potions,omitempty"`
 }
 
 func handleSaveBuild(w http.ResponseWriter, r *http.Request) {
@@ -275,6 +335,40 @@ func handleSaveBuild(w http.ResponseWriter, r *http.Request) {
 	if req.Stamina < 1 {
 		req.Stamina = 1
 	}
+	var slots []string
+	if len(req.Equipment) > 0 || len(req.Potions) > 0 {
+		items, err := loadItemLookup()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := validateLoadout(req.Equipment, items); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validatePotions(req.Potions, items); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, eq := range req.Equipment {
+			slots = append(slots, items[eq.ItemID].Type)
+		}
+	}
+	if len(req.Blessings) > 0 {
+		perks, err := getAllPerks()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		byID := make(map[int]Perk, len(perks))
+		for _, p := range perks {
+			byID[p.ID] = p
+		}
+		if err := validateBlessings(req.Blessings, byID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	var buildID int64
 	if err := withTx(func(tx *sql.Tx) error {
@@ -292,6 +386,15 @@ func handleSaveBuild(w http.ResponseWriter, r *http.Request) {
 			if _, err := tx.Exec(`DELETE FROM tooling.build_talents WHERE build_id=$1`, buildID); err != nil {
 				return err
 			}
+			if _, err := tx.Exec(`DELETE FROM tooling.build_items WHERE build_id=$1`, buildID); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`DELETE FROM tooling.build_blessings WHERE build_id=$1`, buildID); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`DELETE FROM tooling.build_potions WHERE build_id=$1`, buildID); err != nil {
+				return err
+			}
 		} else {
 			if err := tx.QueryRow(`
 				INSERT INTO tooling.builds
@@ -316,6 +419,30 @@ func handleSaveBuild(w http.ResponseWriter, r *http.Request) {
 				return fmt.Errorf("talent insert: %w", err)
 			}
 		}
+		for i, eq := range req.Equipment {
+			if _, err := tx.Exec(`
+				INSERT INTO tooling.build_items (build_id, slot, item_id, gem_id)
+				VALUES ($1,$2,$3,$4)`,
+				buildID, slots[i], eq.ItemID, eq.GemID); err != nil {
+				return fmt.Errorf("item insert: %w", err)
+			}
+		}
+		for _, perkID := range req.Blessings {
+			if _, err := tx.Exec(`
+				INSERT INTO tooling.build_blessings (build_id, perk_id)
+				VALUES ($1,$2)`,
+				buildID, perkID); err != nil {
+				return fmt.Errorf("blessing insert: %w", err)
+			}
+		}
+		for i, p := range req.Potions {
+			if _, err := tx.Exec(`
+				INSERT INTO tooling.build_potions (build_id, potion_order, item_id, hp_below_percent)
+				VALUES ($1,$2,$3,$4)`,
+				buildID, i+1, p.ItemID, p.HPBelowPercent); err != nil {
+				return fmt.Errorf("potion insert: %w", err)
+			}
+		}
 		return nil
 	}); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -432,6 +559,63 @@ func loadAllBuilds() ([]Build, error) {
 			builds[i].Talents = append(builds[i].Talents, bt)
 		}
 	}
+
+	iRows, err := db.Query(`
+		SELECT build_id, item_id, gem_id
+		FROM tooling.build_items
+		ORDER BY build_id, slot`)
+	if err != nil {
+		return nil, err
+	}
+	defer iRows.Close()
+	for iRows.Next() {
+		var buildID int64
+		var eq EquippedItem
+		if err := iRows.Scan(&buildID, &eq.ItemID, &eq.GemID); err != nil {
+			return nil, err
+		}
+		if i, ok := idMap[buildID]; ok {
+			builds[i].Equipment = append(builds[i].Equipment, eq)
+		}
+	}
+
+	bRows, err := db.Query(`
+		SELECT build_id, perk_id
+		FROM tooling.build_blessings
+		ORDER BY build_id, perk_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer bRows.Close()
+	for bRows.Next() {
+		var buildID int64
+		var perkID int
+		if err := bRows.Scan(&buildID, &perkID); err != nil {
+			return nil, err
+		}
+		if i, ok := idMap[buildID]; ok {
+			builds[i].Blessings = append(builds[i].Blessings, perkID)
+		}
+	}
+
+	pRows, err := db.Query(`
+		SELECT build_id, item_id, hp_below_percent
+		FROM tooling.build_potions
+		ORDER BY build_id, potion_order`)
+	if err != nil {
+		return nil, err
+	}
+	defer pRows.Close()
+	for pRows.Next() {
+		var buildID int64
+		var p CombatPotion
+		if err := pRows.Scan(&buildID, &p.ItemID, &p.HPBelowPercent); err != nil {
+			return nil, err
+		}
+		if i, ok := idMap[buildID]; ok {
+			builds[i].Potions = append(builds[i].Potions, p)
+		}
+	}
 	return builds, nil
 }
 
@@ -462,6 +646,50 @@ func loadBuild(id int64) (*Build, error) {
 		}
 		b.Talents = append(b.Talents, bt)
 	}
+	iRows, err := db.Query(`
+		SELECT item_id, gem_id
+		FROM tooling.build_items WHERE build_id=$1
+		ORDER BY slot`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer iRows.Close()
+	for iRows.Next() {
+		var eq EquippedItem
+		if err := iRows.Scan(&eq.ItemID, &eq.GemID); err != nil {
+			return nil, err
+		}
+		b.Equipment = append(b.Equipment, eq)
+	}
+	bRows, err := db.Query(`
+		SELECT perk_id FROM tooling.build_blessings WHERE build_id=$1
+		ORDER BY perk_id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer bRows.Close()
+	for bRows.Next() {
+		var perkID int
+		if err := bRows.Scan(&perkID); err != nil {
+			return nil, err
+		}
+		b.Blessings = append(b.Blessings, perkID)
+	}
+	pRows, err := db.Query(`
+		SELECT item_id, hp_below_percent
+		FROM tooling.build_potions WHERE build_id=$1
+		ORDER BY potion_order`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer pRows.Close()
+	for pRows.Next() {
+		var p CombatPotion
+		if err := pRows.Scan(&p.ItemID, &p.HPBelowPercent); err != nil {
+			return nil, err
+		}
+		b.Potions = append(b.Potions, p)
+	}
 	return &b, nil
 }
 
@@ -553,12 +781,25 @@ func handleStartBuildRun(w http.ResponseWriter, r *http.Request) {
 		ids = append(ids, b.BuildID)
 	}
 
+	seed := newCombatSeed()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	rules, err := loadCombatRules(req.RulesID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	cfg := BuildRunConfig{
 		Milestones:    req.Milestones,
 		Rounds:        req.Rounds,
 		FightsPerPair: req.FightsPerPair,
 		BuildIDs:      ids,
 		BuildNames:    names,
+		Seed:          seed,
+		RulesID:       req.RulesID,
+		Rules:         &rules,
 		StartedAt:     time.Now().UTC(),
 	}
 	cfgJSON, _ := json.Marshal(cfg)
@@ -603,6 +844,7 @@ func handleStartBuildRun(w http.ResponseWriter, r *http.Request) {
 		"totalMatches": totalMatches,
 		"buildCount":   len(participants),
 		"milestones":   req.Milestones,
+		"seed":         seed,
 	})
 }
 
@@ -612,6 +854,7 @@ type buildStanding struct {
 	rating    float64
 	wins      int
 	losses    int
+	metrics   CombatMetrics
 }
 
 func runBuildTournament(runID int64, builds []Build, cfg BuildRunConfig, tracker *buildProgressTracker) {
@@ -631,8 +874,15 @@ func runBuildTournament(runID int64, builds []Build, cfg BuildRunConfig, tracker
 		_, _ = db.Exec(`UPDATE tooling.build_runs SET status='failed', finished_at=NOW() WHERE run_id=$1`, runID)
 		return
 	}
+	items, err := loadItemLookup()
+	if err != nil {
+		log.Printf("build_tournament: item load failed: %v", err)
+		_, _ = db.Exec(`UPDATE tooling.build_runs SET status='failed', finished_at=NOW() WHERE run_id=$1`, runID)
+		return
+	}
 
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	rules := rulesOrDefault(cfg.Rules)
 	const k = 32.0
 	flushEvery := 4
 
@@ -644,7 +894,7 @@ func runBuildTournament(runID int64, builds []Build, cfg BuildRunConfig, tracker
 		for _, b := range builds {
 			bb := b
 			st := &buildStanding{build: bb, rating: 1000}
-			st.character = snapshotBuild(int(bb.BuildID), &bb, day, talents, effects, perks)
+			st.character = snapshotBuild(int(bb.BuildID), &bb, day, talents, effects, perks, items)
 			standings = append(standings, st)
 		}
 
@@ -660,7 +910,7 @@ func runBuildTournament(runID int64, builds []Build, cfg BuildRunConfig, tracker
 			for i := 0; i+1 < len(standings); i += 2 {
 				a := standings[i]
 				b := standings[i+1]
-				winsA, winsB := runBuildMatch(a.character, b.character, cfg.FightsPerPair)
+				winsA, winsB := runBuildMatch(a.character, b.character, cfg.FightsPerPair, rules, rng, &a.metrics, &b.metrics)
 				a.wins += winsA
 				a.losses += winsB
 				b.wins += winsB
@@ -681,11 +931,12 @@ func runBuildTournament(runID int64, builds []Build, cfg BuildRunConfig, tracker
 			return standings[i].rating > standings[j].rating
 		})
 		for idx, s := range standings {
+			metrics, _ := json.Marshal(s.metrics.summary())
 			_, err := db.Exec(`
 				UPDATE tooling.build_results
-				SET rating=$1, wins=$2, losses=$3, rank=$4
-				WHERE run_id=$5 AND build_id=$6 AND milestone_day=$7`,
-				s.rating, s.wins, s.losses, idx+1, runID, s.build.BuildID, day)
+				SET rating=$1, wins=$2, losses=$3, rank=$4, metrics=$5::jsonb
+				WHERE run_id=$6 AND build_id=$7 AND milestone_day=$8`,
+				s.rating, s.wins, s.losses, idx+1, string(metrics), runID, s.build.BuildID, day)
 			if err != nil {
 				log.Printf("build_tournament: result write failed (build=%d day=%d): %v", s.build.BuildID, day, err)
 			}
@@ -709,21 +960,23 @@ func runBuildTournament(runID int64, builds []Build, cfg BuildRunConfig, tracker
 
 // runBuildMatch is identical in spirit to runMatch but takes pre-built
 // CombatCharacters (so we don't re-snapshot for every fight).
-func runBuildMatch(a, b *CombatCharacter, fights int) (int, int) {
+func runBuildMatch(a, b *CombatCharacter, fights int, rules CombatRules, rng *rand.Rand, metricsA, metricsB *CombatMetrics) (int, int) {
+	// Copies numbered 1 and 2 so the winner's ID tells the sides apart
+	c1 := cloneCombatant(1, a)
+	c2 := cloneCombatant(2, b)
+	sim := newCombatSimulator(rules, matchObservers(metricsA, metricsB)...)
+	countFights(fights, metricsA, metricsB)
+
 	winsA, winsB := 0, 0
 	for i := 0; i < fights; i++ {
-		// Fresh copies — executeCombat mutates DepletedHealth and effect state.
-		c1 := cloneCombatant(1, a)
-		c2 := cloneCombatant(2, b)
-		var result map[string]interface{}
+		seed := rng.Int63()
+		var winner int
 		if i%2 == 0 {
-			result = executeCombat(c1, c2)
+			winner = sim.winner(c1, c2, seed)
 		} else {
-			result = executeCombat(c2, c1)
+			winner = sim.winner(c2, c1, seed)
 		}
-		header, _ := result["header"].(map[string]interface{})
-		winnerID, _ := header["winnerId"].(int)
-		if winnerID == 1 {
+		if winner == 1 {
 			winsA++
 		} else {
 			winsB++
@@ -847,7 +1100,7 @@ func handleGetBuildRun(w http.ResponseWriter, r *http.Request) {
 	}
 
 	rows, err := db.Query(`
-		SELECT build_id, milestone_day, rating, wins, losses, COALESCE(rank, 0)
+		SELECT build_id, milestone_day, rating, wins, losses, COALESCE(rank, 0), metrics
 		FROM tooling.build_results
 		WHERE run_id=$1
 		ORDER BY milestone_day ASC, rank ASC`, runID)
@@ -858,10 +1111,14 @@ func handleGetBuildRun(w http.ResponseWriter, r *http.Request) {
 	defer rows.Close()
 	for rows.Next() {
 		var rr BuildResultRow
-		if err := rows.Scan(&rr.BuildID, &rr.MilestoneDay, &rr.Rating, &rr.Wins, &rr.Losses, &rr.Rank); err != nil {
+		var metricsRaw []byte
+		if err := rows.Scan(&rr.BuildID, &rr.MilestoneDay, &rr.Rating, &rr.Wins, &rr.Losses, &rr.Rank, &metricsRaw); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if len(metricsRaw) > 0 {
+			_ = json.Unmarshal(metricsRaw, &rr.Metrics)
+		}
 		if name, ok := run.Config.BuildNames[rr.BuildID]; ok {
 			rr.BuildName = name
 		}
@@ -963,14 +1220,21 @@ func handleAddBuildToRun(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	items, err := loadItemLookup()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	// Mark run running while we add.
 	_, _ = db.Exec(`UPDATE tooling.build_runs SET status='running', finished_at=NULL WHERE run_id=$1`, body.RunID)
 
 	go func() {
 		const k = 32.0
+		// Derived from the run's master seed so adding the same build replays identically.
+		rng := rand.New(rand.NewSource(cfg.Seed + newBuild.BuildID))
 		for _, day := range cfg.Milestones {
-			newChar := snapshotBuild(int(newBuild.BuildID), newBuild, day, talents, effects, perks)
+			newChar := snapshotBuild(int(newBuild.BuildID), newBuild, day, talents, effects, perks, items)
 			// Seed new build's row at 1000.
 			_, _ = db.Exec(`
 				INSERT INTO tooling.build_results (run_id, build_id, milestone_day, rating)
@@ -991,7 +1255,7 @@ func handleAddBuildToRun(w http.ResponseWriter, r *http.Request) {
 				if opp.BuildID == newBuild.BuildID {
 					continue
 				}
-				oppChar := snapshotBuild(int(opp.BuildID), &opp, day, talents, effects, perks)
+				oppChar := snapshotBuild(int(opp.BuildID), &opp, day, talents, effects, perks, items)
 
 				var oppRating float64
 				var oppWins, oppLosses int
@@ -1003,7 +1267,7 @@ func handleAddBuildToRun(w http.ResponseWriter, r *http.Request) {
 					oppRating = 1000
 				}
 
-				wA, wB := runBuildMatch(newChar, oppChar, cfg.FightsPerPair)
+				wA, wB := runBuildMatch(newChar, oppChar, cfg.FightsPerPair, rulesOrDefault(cfg.Rules), rng, nil, nil)
 				newRating, oppRating = updateElo(newRating, oppRating, wA, wB, k)
 				newWins += wA
 				newLosses += wB
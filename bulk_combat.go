@@ -49,6 +49,9 @@ type BulkCombatConfig struct {
 	ValueMax      float64            `json:"valueMax"`
 	EffectIDs     []int              `json:"effectIds"`
 	IncludedNames map[int]string     `json:"includedNames"`
+	Seed          int64              `json:"seed"` // master seed; every fight seed is drawn from it
+	RulesID       *int64             `json:"rulesProfileId,omitempty"`
+	Rules         *CombatRules       `json:"rules,omitempty"` // snapshot taken at start so later profile edits don't change the run
 	StartedAt     time.Time          `json:"startedAt"`
 }
 
@@ -61,7 +64,9 @@ type StartBulkRequest struct {
 	Phases        int                `json:"phases"`
 	ValueMin      float64            `json:"valueMin"`
 	ValueMax      float64            `json:"valueMax"`
-	EffectIDs     []int              `json:"effectIds"` // empty = all effects
+	EffectIDs     []int              `json:"effectIds"`                // empty = all effects
+	Seed          *int64             `json:"seed,omitempty"`           // nil = random; pass a stored seed to reproduce a run
+	RulesID       *int64             `json:"rulesProfileId,omitempty"` // nil = default rules
 }
 
 // PhaseSnapshot is the per-phase state of one effect
@@ -71,6 +76,8 @@ type PhaseSnapshot struct {
 	Rating float64 `json:"rating"`
 	Wins   int     `json:"wins"`
 	Losses int     `json:"losses"`
+
+	Metrics *CombatMetricsSummary `json:"metrics,omitempty"` // time to kill and damage by source over the phase
 }
 
 // BulkCombatResultRow is one effect's standing in a run
@@ -189,6 +196,16 @@ func handleStartBulkCombat(w http.ResponseWriter, r *http.Request) {
 		ids = append(ids, e.ID)
 	}
 
+	seed := newCombatSeed()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	rules, err := loadCombatRules(req.RulesID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	cfg := BulkCombatConfig{
 		Baseline:      req.Baseline,
 		EffectValue:   req.EffectValue,
@@ -199,6 +216,9 @@ func handleStartBulkCombat(w http.ResponseWriter, r *http.Request) {
 		ValueMax:      req.ValueMax,
 		EffectIDs:     ids,
 		IncludedNames: names,
+		Seed:          seed,
+		RulesID:       req.RulesID,
+		Rules:         &rules,
 		StartedAt:     time.Now().UTC(),
 	}
 	cfgJSON, _ := json.Marshal(cfg)
@@ -241,6 +261,7 @@ func handleStartBulkCombat(w http.ResponseWriter, r *http.Request) {
 		"totalMatches": totalMatches,
 		"effectCount":  len(participants),
 		"phases":       req.Phases,
+		"seed":         seed,
 	})
 }
 
@@ -389,25 +410,10 @@ type effectStanding struct {
 	totalWins    int             // accumulated across phases
 	totalLosses  int             // accumulated across phases
 	phaseHistory []PhaseSnapshot // snapshots after each phase
+	metrics      CombatMetrics   // current phase only
 }
 
 func buildBulkCombatant(id int, baseline BulkCombatBaseline, e Effect, value float64) *CombatCharacter {
-	core := ""
-	if e.CoreEffectCode != nil {
-		core = *e.CoreEffectCode
-	}
-	trigger := "passive"
-	if e.TriggerType != nil && *e.TriggerType != "" {
-		trigger = *e.TriggerType
-	}
-	factorType := "percent"
-	if e.FactorType != nil && *e.FactorType != "" {
-		factorType = *e.FactorType
-	}
-	targetSelf := false
-	if e.TargetSelf != nil {
-		targetSelf = *e.TargetSelf
-	}
 	return &CombatCharacter{
 		CharacterID:   id,
 		CharacterName: fmt.Sprintf("E%d", e.ID),
@@ -418,39 +424,33 @@ func buildBulkCombatant(id int, baseline BulkCombatBaseline, e Effect, value flo
 		Armor:         baseline.Armor,
 		MinDamage:     baseline.MinDamage,
 		MaxDamage:     baseline.MaxDamage,
-		Effects: []CombatTestEffect{{
-			EffectID:       1,
-			CoreEffectCode: core,
-			TriggerType:    trigger,
-			FactorType:     factorType,
-			TargetSelf:     targetSelf,
-			ConditionType:  e.ConditionType,
-			ConditionValue: e.ConditionValue,
-			Duration:       e.Duration,
-			Value:          int(math.Round(value)),
-		}},
+		Effects:       []CombatTestEffect{effectFromTemplate(1, e, int(math.Round(value)))},
 	}
 }
 
 // runMatch runs `fights` fights between two effects at their current values.
 // Alternates first-strike side across fights to remove turn-order bias.
+// Each fight is seeded from `rng`, so a run seeded identically replays exactly.
 // Returns winsA, winsB (draws are impossible — any tie counts toward the
-// player who acted first, mirroring engine behaviour).
-func runMatch(a, b Effect, valA, valB float64, baseline BulkCombatBaseline, fights int) (int, int) {
+// player who acted first, mirroring engine behaviour). Non-nil metricsA/B
+// collect each side's time to kill and damage by source.
+func runMatch(a, b Effect, valA, valB float64, baseline BulkCombatBaseline, fights int, rules CombatRules, rng *rand.Rand, metricsA, metricsB *CombatMetrics) (int, int) {
+	c1 := buildBulkCombatant(1, baseline, a, valA)
+	c2 := buildBulkCombatant(2, baseline, b, valB)
+	sim := newCombatSimulator(rules, matchObservers(metricsA, metricsB)...)
+	countFights(fights, metricsA, metricsB)
+
 	winsA, winsB := 0, 0
 	for i := 0; i < fights; i++ {
-		c1 := buildBulkCombatant(1, baseline, a, valA)
-		c2 := buildBulkCombatant(2, baseline, b, valB)
-		var result map[string]interface{}
+		seed := rng.Int63()
+		var winner int
 		if i%2 == 0 {
-			result = executeCombat(c1, c2)
+			winner = sim.winner(c1, c2, seed)
 		} else {
-			result = executeCombat(c2, c1)
+			winner = sim.winner(c2, c1, seed)
 		}
-		header, _ := result["header"].(map[string]interface{})
-		winnerID, _ := header["winnerId"].(int)
 		// CharacterID 1 = effect A, 2 = effect B regardless of strike order.
-		if winnerID == 1 {
+		if winner == 1 {
 			winsA++
 		} else {
 			winsB++
@@ -488,7 +488,8 @@ func runBulkCombatSimulation(runID int64, effects []Effect, cfg BulkCombatConfig
 		standings = append(standings, &effectStanding{effect: e, rating: 1000, value: cfg.EffectValue})
 	}
 
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	rules := rulesOrDefault(cfg.Rules)
 	const k = 32.0
 
 	// α (learning rate) decay schedule. Damps oscillation.
@@ -509,6 +510,7 @@ func runBulkCombatSimulation(runID int64, effects []Effect, cfg BulkCombatConfig
 			s.rating = 1000
 			s.wins = 0
 			s.losses = 0
+			s.metrics = CombatMetrics{}
 		}
 
 		for round := 0; round < cfg.Rounds; round++ {
@@ -522,7 +524,7 @@ func runBulkCombatSimulation(runID int64, effects []Effect, cfg BulkCombatConfig
 			for i := 0; i+1 < len(standings); i += 2 {
 				a := standings[i]
 				b := standings[i+1]
-				winsA, winsB := runMatch(a.effect, b.effect, a.value, b.value, cfg.Baseline, cfg.FightsPerPair)
+				winsA, winsB := runMatch(a.effect, b.effect, a.value, b.value, cfg.Baseline, cfg.FightsPerPair, rules, rng, &a.metrics, &b.metrics)
 				a.wins += winsA
 				a.losses += winsB
 				b.wins += winsB
@@ -561,6 +563,8 @@ func runBulkCombatSimulation(runID int64, effects []Effect, cfg BulkCombatConfig
 				Rating: s.rating,
 				Wins:   s.wins,
 				Losses: s.losses,
+
+				Metrics: s.metrics.summary(),
 			})
 			// Skip adjustment on the last phase — we want the final readings unchanged.
 			if phase == cfg.Phases-1 {
@@ -2,22 +2,35 @@ package main
 
 import (
 	"encoding/json"
-	"log"
-	"math"
 	"math/rand"
 	"net/http"
 )
 
 // ── Combat types ────────────────────────────────────────────────────────────
 
-// CombatTestRequest is the JSON body for the /api/testCombat endpoint
+// CombatTestRequest is the JSON body for the /api/testCombat and /api/replayCombat endpoints
 type CombatTestRequest struct {
 	Combatant1 CombatTestCombatant `json:"combatant1"`
 	Combatant2 CombatTestCombatant `json:"combatant2"`
+	Seed       *int64              `json:"seed,omitempty"`           // nil = random; required for replay
+	RulesID    *int64              `json:"rulesProfileId,omitempty"` // tooling.combat_rules profile; nil = defaults
+	Narrate    bool                `json:"narrate,omitempty"`        // add a readable narration of the log to the result
 }
 
-// CombatTestCombatant represents one side's configuration for a test fight
+// CombatTestCombatant represents one side's configuration for a test fight:
+// its own stats and effects, or a reference to a stored character (see
+// combat_sources.go), in which case the fields below the references are
+// ignored. Equipment, blessings and potions come along either way and
+// replace a build's own.
 type CombatTestCombatant struct {
+	EnemyID        *int           `json:"enemyId,omitempty"`        // game.enemies row, with its talents and perks
+	PendingEnemyID *int           `json:"pendingEnemyId,omitempty"` // tooling.enemies row, with its talents and perks
+	BuildID        *int64         `json:"buildId,omitempty"`        // tooling.builds row, snapshotted on BuildDay
+	BuildDay       int            `json:"buildDay,omitempty"`       // 0 = the end of the build's career (day 70)
+	Equipment      []EquippedItem `json:"equipment,omitempty"`      // game.items worn (see combat_equipment.go)
+	Blessings      []int          `json:"blessings,omitempty"`      // blessing perk IDs (see combat_consumables.go)
+	Potions        []CombatPotion `json:"potions,omitempty"`        // potions carried into the fight
+
 	Name      string             `json:"name"`
 	Strength  int                `json:"strength"`
 	Stamina   int                `json:"stamina"`
@@ -27,19 +40,37 @@ type CombatTestCombatant struct {
 	MinDamage int                `json:"minDamage"`
 	MaxDamage int                `json:"maxDamage"`
 	Effects   []CombatTestEffect `json:"effects"`
+	Talents   []BuildTalent      `json:"talents,omitempty"` // talent picks, resolved like an enemy's (see combat_sources.go)
 }
 
 // CombatTestEffect is a simplified effect sent from the UI
 type CombatTestEffect struct {
-	EffectID       int     `json:"effectId"`
-	CoreEffectCode string  `json:"coreEffectCode"` // attack, stun, bleed, dodge, crit, counterattack, double_attack, modify_damage, modify_dodge, modify_crit, modify_armor, modify_heal, consecutive_damage
-	TriggerType    string  `json:"triggerType"`    // passive, on_start, on_attack, on_hit, on_crit, on_crit_taken, on_hit_taken, on_turn_start, on_turn_end, on_every_other_turn
-	FactorType     string  `json:"factorType"`     // percent, percent_of_max_hp, percent_of_missing_hp, percent_of_damage_dealt, percent_of_damage_taken
-	TargetSelf     bool    `json:"targetSelf"`
-	ConditionType  *string `json:"conditionType,omitempty"`
-	ConditionValue *int    `json:"conditionValue,omitempty"`
-	Duration       *int    `json:"duration,omitempty"`
-	Value          int     `json:"value"`
+	EffectID       int              `json:"effectId"`
+	CoreEffectCode string           `json:"coreEffectCode"` // attack, stun, bleed, poison, burn, shield, dodge, crit, counterattack, double_attack, modify_damage, modify_dodge, modify_crit, modify_armor, modify_heal, modify_heal_done, anti_heal, consecutive_damage, lifesteal, reflect_damage, armor_penetration, stun_resist, cleanse, dispel
+	TriggerType    string           `json:"triggerType"`    // passive, on_start, on_attack, on_hit, on_crit, on_crit_taken, on_hit_taken, on_turn_start, on_turn_end, on_every_other_turn
+	FactorType     string           `json:"factorType"`     // percent, flat, percent_of_max_hp, percent_of_missing_hp, percent_of_damage_dealt, percent_of_damage_taken
+	TargetSelf     bool             `json:"targetSelf"`
+	ConditionType  *string          `json:"conditionType,omitempty"`
+	ConditionValue *int             `json:"conditionValue,omitempty"`
+	Condition      *EffectCondition `json:"condition,omitempty"`  // structured condition, see combat_conditions.go
+	Cooldown       int              `json:"cooldown,omitempty"`   // turns before it can proc again
+	MaxProcs       int              `json:"maxProcs,omitempty"`   // procs per fight, 0 = unlimited
+	ProcChance     *int             `json:"procChance,omitempty"` // % chance to proc when triggered, nil = always
+	Duration       *int             `json:"duration,omitempty"`
+	Value          int              `json:"value"`
+	AoE            bool             `json:"aoe,omitempty"` // lands on every living enemy, or every living ally with targetSelf
+
+	// DoT fields (bleed, poison, burn); Duration is the number of ticks
+	Stacking    string `json:"stacking,omitempty"`    // stack (default), refresh, max_stacks
+	MaxStacks   int    `json:"maxStacks,omitempty"`   // cap for max_stacks
+	Decay       int    `json:"decay,omitempty"`       // damage lost after each tick
+	IgnoreArmor *bool  `json:"ignoreArmor,omitempty"` // nil = the DoT type's default (only burn is mitigated)
+
+	RemoveOrder string `json:"removeOrder,omitempty"` // cleanse/dispel: newest (default), strongest
+
+	Consumable bool `json:"consumable,omitempty"` // procs once and is logged as a potion (see combat_consumables.go)
+
+	SourceEffectID int `json:"sourceEffectId,omitempty"` // the game.effects row it was built from, for naming it
 }
 
 // CombatCharacter represents a character's stats, effects, and header info for combat
@@ -64,22 +95,173 @@ type CombatModifiers struct {
 	DamageModifier         int
 	DamageTakenMod         int
 	ArmorModifier          int
-	HealModifier           int
+	HealModifier           int // % healing received
+	HealDoneModifier       int // % healing done
+	AntiHeal               int // % healing received that is blocked (capped at 100)
 	CounterChance          int
 	DoubleAttackChance     int
 	ConsecutiveDamageBonus int // % damage increase per consecutive hit
+	Lifesteal              int // % of HP damage dealt by attacks healed back
+	ReflectDamage          int // % of attack damage taken sent back to the attacker
+	ArmorPenetration       int // % of the defender's armor ignored by attacks
+	StunResist             int // points taken off the chance of stuns against this fighter
 }
 
 // CombatLogEntry represents a single event in the combat log
 type CombatLogEntry struct {
 	Turn        int    `json:"turn"`
-	CharacterID int    `json:"characterId"`
-	Action      string `json:"action"` // attack, crit, dodge, stun, stunned, bleed, counterattack, double_attack, heal, buff, buff_expire
+	CharacterID int    `json:"characterId"` // actor; the wearer for buff (the caster for on_start ones), shield and DoT tick entries
+	TargetID    int    `json:"targetId,omitempty"`
+	Action      string `json:"action"` // attack, crit, dodge, stun, stunned, stun_resisted, stun_immune, stun_immunity, cleanse, dispel, buff_removed, bleed, poison, burn, counterattack, double_attack, heal, lifesteal, reflect_damage, armor_penetration, buff, buff_expire, initiative, shield, shield_break, potion
 	Factor      int    `json:"factor"`
 	EffectID    *int   `json:"effectId,omitempty"`
 	TriggerType string `json:"triggerType,omitempty"` // on_start, on_hit, on_crit, on_crit_taken, on_hit_taken, on_turn_start, on_turn_end, on_every_other_turn
 	Duration    *int   `json:"duration,omitempty"`    // how many turns a buff lasts (for buff/buff_expire actions)
-	BuffType    string `json:"buffType,omitempty"`    // which modifier: modify_damage, modify_dodge, modify_crit, modify_armor, modify_heal
+	BuffType    string `json:"buffType,omitempty"`    // which modifier: modify_damage, modify_dodge, modify_crit, modify_armor, modify_heal, shield, or the DoT type
+	Absorbed    int    `json:"absorbed,omitempty"`    // part of Factor soaked up by the target's shield (damage actions)
+	Overheal    int    `json:"overheal,omitempty"`    // part of Factor above the target's max HP (heal actions)
+	SourceID    int    `json:"sourceId,omitempty"`    // caster of a buff or shield worn by someone else (CharacterID is the wearer)
+}
+
+// CombatResult is the full outcome of one fight (the /api/testCombat response)
+type CombatResult struct {
+	Header    CombatResultHeader    `json:"header"`
+	Log       []CombatLogEntry      `json:"log"`
+	Stats     CombatResultStats     `json:"stats"`
+	Timeline  []TimelinePoint       `json:"timeline"` // fighters in header order: combatant1, combatant2
+	Breakdown CombatResultBreakdown `json:"breakdown"`
+	Narration []string              `json:"narration,omitempty"` // only when the request asked for it
+}
+
+// CombatResultHeader holds the winner, the seed that reproduces the fight, and both sides' summaries
+type CombatResultHeader struct {
+	WinnerID   int              `json:"winnerId"`
+	Seed       int64            `json:"seed"`
+	Turns      int              `json:"turns"`    // last turn played
+	TimedOut   bool             `json:"timedOut"` // nobody died within the turn limit; winner decided on HP
+	Combatant1 CombatantSummary `json:"combatant1"`
+	Combatant2 CombatantSummary `json:"combatant2"`
+}
+
+// CombatantSummary is one side's identity and HP at the end of a fight
+type CombatantSummary struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	MaxHP int    `json:"maxHp"`
+	HPEnd int    `json:"hpEnd"`
+}
+
+// CombatResultStats pairs the per-side statistics of a fight
+type CombatResultStats struct {
+	Combatant1 CombatStats `json:"combatant1"`
+	Combatant2 CombatStats `json:"combatant2"`
+}
+
+// CombatResultBreakdown pairs the per-side damage/heal breakdowns of a fight
+type CombatResultBreakdown struct {
+	Combatant1 DamageBreakdown `json:"combatant1"`
+	Combatant2 DamageBreakdown `json:"combatant2"`
+}
+
+// CombatStats are the running totals collected for one side during a fight
+type CombatStats struct {
+	DamageDealt      int `json:"damageDealt"`
+	DamageTaken      int `json:"damageTaken"`
+	HealingDone      int `json:"healingDone"` // effective healing, overheal excluded
+	Overheal         int `json:"overheal"`
+	HealingBlocked   int `json:"healingBlocked"` // healing this side didn't receive because of anti-heal
+	Attacks          int `json:"attacks"`
+	CritHits         int `json:"critHits"`
+	DodgedAttacks    int `json:"dodgedAttacks"`
+	AttacksDodged    int `json:"attacksDodged"` // times this char's attack was dodged
+	StunApplied      int `json:"stunApplied"`
+	TimesStunned     int `json:"timesStunned"`
+	StunsResisted    int `json:"stunsResisted"` // stuns that rolled but failed on stun_resist or diminishing returns
+	StunsImmune      int `json:"stunsImmune"`   // stuns that rolled during this side's immunity window
+	BleedApplied     int `json:"bleedApplied"`
+	PoisonApplied    int `json:"poisonApplied"`
+	BurnApplied      int `json:"burnApplied"`
+	CounterHits      int `json:"counterHits"`
+	DoubleAttacks    int `json:"doubleAttacks"`
+	MaxConsecHits    int `json:"maxConsecHits"`
+	ShieldAbsorbed   int `json:"shieldAbsorbed"`   // damage this side's shields soaked up
+	LifestealHealing int `json:"lifestealHealing"` // part of HealingDone that came from lifesteal
+	DamageReflected  int `json:"damageReflected"`  // part of DamageDealt that came from reflect_damage
+	DebuffsCleansed  int `json:"debuffsCleansed"`  // debuffs and DoT stacks this side's cleanses removed
+	BuffsDispelled   int `json:"buffsDispelled"`   // buffs this side's dispels removed
+	PotionsUsed      int `json:"potionsUsed"`
+
+	Procs map[int]int `json:"procs,omitempty"` // triggered procs per effect ID
+}
+
+// Add accumulates another fight's stats (MaxConsecHits keeps the best streak)
+func (s *CombatStats) Add(o CombatStats) {
+	s.DamageDealt += o.DamageDealt
+	s.DamageTaken += o.DamageTaken
+	s.HealingDone += o.HealingDone
+	s.Overheal += o.Overheal
+	s.HealingBlocked += o.HealingBlocked
+	s.Attacks += o.Attacks
+	s.CritHits += o.CritHits
+	s.DodgedAttacks += o.DodgedAttacks
+	s.AttacksDodged += o.AttacksDodged
+	s.StunApplied += o.StunApplied
+	s.TimesStunned += o.TimesStunned
+	s.StunsResisted += o.StunsResisted
+	s.StunsImmune += o.StunsImmune
+	s.BleedApplied += o.BleedApplied
+	s.PoisonApplied += o.PoisonApplied
+	s.BurnApplied += o.BurnApplied
+	s.CounterHits += o.CounterHits
+	s.DoubleAttacks += o.DoubleAttacks
+	s.ShieldAbsorbed += o.ShieldAbsorbed
+	s.LifestealHealing += o.LifestealHealing
+	s.DamageReflected += o.DamageReflected
+	s.DebuffsCleansed += o.DebuffsCleansed
+	s.BuffsDispelled += o.BuffsDispelled
+	s.PotionsUsed += o.PotionsUsed
+	for id, n := range o.Procs {
+		if s.Procs == nil {
+			s.Procs = map[int]int{}
+		}
+		s.Procs[id] += n
+	}
+	if o.MaxConsecHits > s.MaxConsecHits {
+		s.MaxConsecHits = o.MaxConsecHits
+	}
+}
+
+// StatsFor returns the stats of the side with the given character ID (nil if neither)
+func (r *CombatResult) StatsFor(charID int) *CombatStats {
+	switch charID {
+	case r.Header.Combatant1.ID:
+		return &r.Stats.Combatant1
+	case r.Header.Combatant2.ID:
+		return &r.Stats.Combatant2
+	}
+	return nil
+}
+
+// SummaryFor returns the header summary of the side with the given character ID (nil if neither)
+func (r *CombatResult) SummaryFor(charID int) *CombatantSummary {
+	switch charID {
+	case r.Header.Combatant1.ID:
+		return &r.Header.Combatant1
+	case r.Header.Combatant2.ID:
+		return &r.Header.Combatant2
+	}
+	return nil
+}
+
+// BreakdownFor returns the breakdown of the side with the given character ID (nil if neither)
+func (r *CombatResult) BreakdownFor(charID int) DamageBreakdown {
+	switch charID {
+	case r.Header.Combatant1.ID:
+		return r.Breakdown.Combatant1
+	case r.Header.Combatant2.ID:
+		return r.Breakdown.Combatant2
+	}
+	return nil
 }
 
 // TempBuff represents a temporary modifier active for a limited number of turns
@@ -89,6 +271,7 @@ type TempBuff struct {
 	Remaining    int // turns remaining (decremented at end of owner's turn)
 	EffectID     int
 	TriggerType  string // what triggered it
+	applied      int    // combatEngine.applied stamp, for cleanse/dispel ordering
 }
 
 // ── Handler ─────────────────────────────────────────────────────────────────
@@ -104,87 +287,148 @@ func handleTestCombat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build combat characters from request
-	c1 := &CombatCharacter{
-		CharacterID:   1,
-		CharacterName: req.Combatant1.Name,
-		Strength:      req.Combatant1.Strength,
-		Stamina:       req.Combatant1.Stamina,
-		Agility:       req.Combatant1.Agility,
-		Luck:          req.Combatant1.Luck,
-		Armor:         req.Combatant1.Armor,
-		MinDamage:     req.Combatant1.MinDamage,
-		MaxDamage:     req.Combatant1.MaxDamage,
-		Effects:       req.Combatant1.Effects,
-	}
-	c2 := &CombatCharacter{
-		CharacterID:   2,
-		CharacterName: req.Combatant2.Name,
-		Strength:      req.Combatant2.Strength,
-		Stamina:       req.Combatant2.Stamina,
-		Agility:       req.Combatant2.Agility,
-		Luck:          req.Combatant2.Luck,
-		Armor:         req.Combatant2.Armor,
-		MinDamage:     req.Combatant2.MinDamage,
-		MaxDamage:     req.Combatant2.MaxDamage,
-		Effects:       req.Combatant2.Effects,
-	}
-
-	result := executeCombat(c1, c2)
+	rules, err := loadCombatRules(req.RulesID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed := newCombatSeed()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	c1, c2, err := buildTestCombatants(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logCombatTest(rules, []*CombatCharacter{c1}, []*CombatCharacter{c2})
+	result := executeCombatWith(c1, c2, CombatOptions{Seed: seed, Rules: rules})
+	narrateTestCombat(&req, result, c1, c2)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
-// ── Combat engine ───────────────────────────────────────────────────────────
+// handleReplayCombat re-runs a CombatTestRequest with the seed taken from a
+// previous result header. Same request + same seed = byte-identical log.
+func handleReplayCombat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req CombatTestRequest
+	if err := decodeJSON(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Seed == nil {
+		http.Error(w, "seed required", http.StatusBadRequest)
+		return
+	}
 
-func resolveCombatModifiers(char *CombatCharacter) CombatModifiers {
-	mods := CombatModifiers{}
-	for _, eff := range char.Effects {
-		if eff.TriggerType != "passive" {
-			continue
-		}
-		switch eff.CoreEffectCode {
-		case "dodge":
-			mods.DodgeChance += eff.Value
-		case "crit":
-			mods.CritChance += eff.Value
-		case "modify_damage":
-			if eff.TargetSelf {
-				mods.DamageModifier += eff.Value
-			}
-		case "modify_armor":
-			mods.ArmorModifier += eff.Value
-		case "modify_heal":
-			mods.HealModifier += eff.Value
-		case "counterattack":
-			mods.CounterChance += eff.Value
-		case "double_attack":
-			mods.DoubleAttackChance += eff.Value
-		case "consecutive_damage":
-			mods.ConsecutiveDamageBonus += eff.Value
-		}
+	rules, err := loadCombatRules(req.RulesID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c1, c2, err := buildTestCombatants(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	return mods
+	logCombatTest(rules, []*CombatCharacter{c1}, []*CombatCharacter{c2})
+	result := executeCombatWith(c1, c2, CombatOptions{Seed: *req.Seed, Rules: rules})
+	narrateTestCombat(&req, result, c1, c2)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
 }
 
-func checkCondition(eff *CombatTestEffect, currentHP int, maxHP int) bool {
-	if eff.ConditionType == nil {
-		return true
+// character turns a test combatant into a combat character with the given ID
+func (c CombatTestCombatant) character(id int) *CombatCharacter {
+	return &CombatCharacter{
+		CharacterID:   id,
+		CharacterName: c.Name,
+		Strength:      c.Strength,
+		Stamina:       c.Stamina,
+		Agility:       c.Agility,
+		Luck:          c.Luck,
+		Armor:         c.Armor,
+		MinDamage:     c.MinDamage,
+		MaxDamage:     c.MaxDamage,
+		Effects:       c.Effects,
 	}
-	switch *eff.ConditionType {
-	case "hp_below_percent":
-		if eff.ConditionValue == nil {
-			return true
-		}
-		return currentHP*100/maxHP < *eff.ConditionValue
-	case "hp_above_percent":
-		if eff.ConditionValue == nil {
-			return true
-		}
-		return currentHP*100/maxHP > *eff.ConditionValue
+}
+
+// ── Combat engine ───────────────────────────────────────────────────────────
+
+// combatant is one fighter's live state during a fight
+type combatant struct {
+	*CombatCharacter
+	team       int // 0 or 1
+	maxHP      int
+	hp         int
+	mods       CombatModifiers
+	buffs      []TempBuff
+	stunTurns  int // own turns still to skip
+	stunImmune int // own turns left in which stuns don't land
+	stunsTaken int // stuns landed on this fighter so far, for diminishing returns
+	dots       []dotStack
+	consecHits int
+	shields    []shieldLayer
+
+	procs            []effectProcs // proc bookkeeping, parallel to Effects
+	attacksStarted   int           // attacks begun this fight, dodged ones included
+	lastAttackDodged bool          // whether the last finished attack was dodged
+	stats            *CombatStats
+}
+
+// shieldLayer is one application of a shield effect; damage drains the
+// oldest layer first
+type shieldLayer struct {
+	points    int
+	remaining int // turns left, 0 = until broken
+	effectID  int
+}
+
+// combatEngine owns the rules, rng, log, turn counter and fighters of one fight
+type combatEngine struct {
+	rules     CombatRules
+	rng       *rand.Rand
+	observers []CombatObserver // the combat log first, then CombatOptions.Observers
+	logging   bool             // some observer reads log entries (see watch)
+	charting  bool             // some observer reads timeline points
+	turn      int
+	fighters  []*combatant    // acting order for fixed initiative: teams interleaved
+	teams     [2][]*combatant // members in the order they were passed
+	targeting [2]string       // each team's targeting policy
+	applied   int             // temp buffs and DoT stacks applied so far
+	ctx       effectContext   // scratch context handed to effect handlers
+
+	procTargets []int // scratch EffectProcEvent.TargetIDs
+}
+
+// CombatOptions configure one fight beyond the two combatants
+type CombatOptions struct {
+	Seed      int64
+	Rules     CombatRules
+	Observers []CombatObserver // notified of every event after the combat log
+}
+
+func newCombatant(char *CombatCharacter, rules CombatRules) *combatant {
+	maxHP, hp := startingHP(char, rules)
+	return &combatant{CombatCharacter: char, maxHP: maxHP, hp: hp, stats: &CombatStats{}, procs: make([]effectProcs, len(char.Effects))}
+}
+
+// startingHP is a character's max HP and the HP it enters a fight with
+func startingHP(char *CombatCharacter, rules CombatRules) (int, int) {
+	maxHP := rules.maxHP(char)
+	hp := maxHP - char.DepletedHealth
+	if hp < 1 {
+		hp = 1
 	}
-	return true
+	return maxHP, hp
 }
 
 func resolveFactorValue(eff *CombatTestEffect, maxHP int, currentHP int, damageContext int) int {
@@ -201,791 +445,408 @@ func resolveFactorValue(eff *CombatTestEffect, maxHP int, currentHP int, damageC
 		return damageContext * eff.Value / 100
 	case "percent_of_damage_taken":
 		return damageContext * eff.Value / 100
-	default: // "percent"
+	default: // "percent", "flat"
 		return eff.Value
 	}
 }
 
-func collectTriggeredEffects(char *CombatCharacter, trigger string) []CombatTestEffect {
-	var result []CombatTestEffect
-	for _, eff := range char.Effects {
-		if eff.TriggerType == trigger {
-			result = append(result, eff)
-		}
-	}
-	return result
-}
-
-func logDamageEntry(turn int, charID int, eff *CombatTestEffect, val int, trigger string) CombatLogEntry {
-	eid := eff.EffectID
-	return CombatLogEntry{Turn: turn, CharacterID: charID, Action: "damage", Factor: val, EffectID: &eid, TriggerType: trigger}
-}
-
-func logHealEntry(turn int, charID int, eff *CombatTestEffect, val int, trigger string) CombatLogEntry {
-	eid := eff.EffectID
-	return CombatLogEntry{Turn: turn, CharacterID: charID, Action: "heal", Factor: val, EffectID: &eid, TriggerType: trigger}
-}
-
-func executeCombat(player *CombatCharacter, enemy *CombatCharacter) map[string]interface{} {
-	playerMods := resolveCombatModifiers(player)
-	enemyMods := resolveCombatModifiers(enemy)
-
-	calculateMaxHP := func(char *CombatCharacter) int {
-		return char.Stamina * 10
-	}
-
-	// Ratio-based chance with diminishing returns:
-	// Equal stats → 10%, double the stat → ~20%, triple → ~27%, clamped [1, 50]
-	statBasedChance := func(myStat int, theirStat int, bonusMod int) int {
-		denom := theirStat
-		if denom < 1 {
-			denom = 1
+// applyPassives applies a combatant's passive effects: to its own modifiers
+// when TargetSelf is set or the effect only ever helps its owner, to every
+// enemy's otherwise
+func (e *combatEngine) applyPassives(c *combatant) {
+	for i := range c.Effects {
+		eff := &c.Effects[i]
+		if eff.TriggerType != "passive" {
+			continue
 		}
-		ratio := float64(myStat) / float64(denom)
-		chance := int(10.0*math.Log2(ratio+1)) + bonusMod
-		if chance < 1 {
-			chance = 1
+		h := effectHandlers[eff.CoreEffectCode]
+		if h == nil || !h.passive {
+			continue
 		}
-		if chance > 50 {
-			chance = 50
+		if eff.TargetSelf || h.ownPassive {
+			e.apply(h, effectContext{effect: eff, trigger: "passive", source: c, target: c})
+			continue
 		}
-		return chance
-	}
-
-	playerMaxHP := calculateMaxHP(player)
-	enemyMaxHP := calculateMaxHP(enemy)
-	playerCurrentHP := playerMaxHP - player.DepletedHealth
-	if playerCurrentHP < 1 {
-		playerCurrentHP = 1
-	}
-	enemyCurrentHP := enemyMaxHP - enemy.DepletedHealth
-	if enemyCurrentHP < 1 {
-		enemyCurrentHP = 1
-	}
-
-	log.Printf("⚔️ Combat Test: %s (%d HP) vs %s (%d HP)",
-		player.CharacterName, playerCurrentHP,
-		enemy.CharacterName, enemyCurrentHP)
-
-	combatLog := []CombatLogEntry{}
-	maxTurns := 30
-	winnerID := 0
-
-	playerStunned := false
-	enemyStunned := false
-	playerBleedStacks := 0
-	enemyBleedStacks := 0
-	playerConsecutiveHits := 0
-	enemyConsecutiveHits := 0
-	playerTempBuffs := []TempBuff{}
-	enemyTempBuffs := []TempBuff{}
-
-	// Helper: apply a modifier effect — if it has a duration, create a temp buff; otherwise apply permanently
-	applyModifier := func(turn int, charID int, mods *CombatModifiers, tempBuffs *[]TempBuff, eff *CombatTestEffect, trigger string) {
-		val := eff.Value
-		hasDuration := eff.Duration != nil && *eff.Duration > 0
-
-		if hasDuration {
-			// Create temporary buff (+1 so the application turn doesn't consume a duration tick)
-			*tempBuffs = append(*tempBuffs, TempBuff{
-				ModifierType: eff.CoreEffectCode,
-				Value:        val,
-				Remaining:    *eff.Duration + 1,
-				EffectID:     eff.EffectID,
-				TriggerType:  trigger,
-			})
-			eid := eff.EffectID
-			dur := *eff.Duration
-			combatLog = append(combatLog, CombatLogEntry{
-				Turn: turn, CharacterID: charID, Action: "buff", Factor: val,
-				EffectID: &eid, TriggerType: trigger, Duration: &dur, BuffType: eff.CoreEffectCode,
-			})
+		if h.selfPassive {
+			continue
 		}
-
-		// Apply to mods immediately (temp buffs also apply right away)
-		switch eff.CoreEffectCode {
-		case "modify_damage":
-			mods.DamageModifier += val
-		case "modify_dodge":
-			mods.DodgeChance += val
-		case "modify_crit":
-			mods.CritChance += val
-		case "modify_armor":
-			mods.ArmorModifier += val
-		case "modify_heal":
-			mods.HealModifier += val
-		case "counterattack":
-			mods.CounterChance += val
-		case "double_attack":
-			mods.DoubleAttackChance += val
-		case "consecutive_damage":
-			mods.ConsecutiveDamageBonus += val
+		for _, enemy := range e.teams[1-c.team] {
+			e.apply(h, effectContext{effect: eff, trigger: "passive", source: c, target: enemy})
 		}
 	}
+}
 
-	// ── Statistics tracking ──────────────────────────
-	type combatStats struct {
-		DamageDealt   int `json:"damageDealt"`
-		DamageTaken   int `json:"damageTaken"`
-		HealingDone   int `json:"healingDone"`
-		Attacks       int `json:"attacks"`
-		CritHits      int `json:"critHits"`
-		DodgedAttacks int `json:"dodgedAttacks"`
-		AttacksDodged int `json:"attacksDodged"` // times this char's attack was dodged
-		StunApplied   int `json:"stunApplied"`
-		TimesStunned  int `json:"timesStunned"`
-		BleedApplied  int `json:"bleedApplied"`
-		CounterHits   int `json:"counterHits"`
-		DoubleAttacks int `json:"doubleAttacks"`
-		MaxConsecHits int `json:"maxConsecHits"`
-	}
-	stats1 := &combatStats{}
-	stats2 := &combatStats{}
-
-	getStats := func(charID int) *combatStats {
-		if charID == player.CharacterID {
-			return stats1
+// fire runs every effect `owner` has for `trigger`. The effect lands on
+// the owner when TargetSelf is set and on `opponent` otherwise; AoE effects
+// land on every living ally (TargetSelf) or enemy instead.
+func (e *combatEngine) fire(trigger string, owner *combatant, opponent *combatant, damage int) {
+	for i := range owner.Effects {
+		eff := &owner.Effects[i]
+		if eff.TriggerType != trigger {
+			continue
 		}
-		return stats2
-	}
-
-	// Fire on_start effects
-	fireStartEffects := func(char *CombatCharacter, charHP *int, charMaxHP int, charMods *CombatModifiers, charBuffs *[]TempBuff,
-		opponentHP *int, opponentMaxHP int, opponentMods *CombatModifiers, opponentBuffs *[]TempBuff,
-		opponentBleed *int, opponentStunned *bool) {
-		for _, eff := range collectTriggeredEffects(char, "on_start") {
-			if !checkCondition(&eff, *charHP, charMaxHP) {
-				continue
+		h := effectHandlers[eff.CoreEffectCode]
+		if h == nil {
+			continue
+		}
+		if eff.AoE {
+			team := 1 - owner.team
+			if eff.TargetSelf {
+				team = owner.team
 			}
-			switch eff.CoreEffectCode {
-			case "damage":
-				val := resolveFactorValue(&eff, charMaxHP, *charHP, 0)
-				if eff.TargetSelf {
-					*charHP -= val
-				} else {
-					*opponentHP -= val
-				}
-				combatLog = append(combatLog, logDamageEntry(0, char.CharacterID, &eff, val, "on_start"))
-			case "heal":
-				val := resolveFactorValue(&eff, charMaxHP, *charHP, 0)
-				if eff.TargetSelf {
-					*charHP += val
-					if val > 0 {
-						getStats(char.CharacterID).HealingDone += val
-					}
-				} else {
-					*opponentHP += val
-				}
-				combatLog = append(combatLog, logHealEntry(0, char.CharacterID, &eff, val, "on_start"))
-			case "bleed":
-				bleedAmount := resolveFactorValue(&eff, opponentMaxHP, *opponentHP, 0)
-				*opponentBleed += bleedAmount
-				getStats(char.CharacterID).BleedApplied += bleedAmount
-				eid := eff.EffectID
-				combatLog = append(combatLog, CombatLogEntry{Turn: 0, CharacterID: char.CharacterID, Action: "bleed", Factor: bleedAmount, EffectID: &eid, TriggerType: "on_start"})
-			case "stun":
-				if rand.Intn(100) < eff.Value {
-					*opponentStunned = true
-					getStats(char.CharacterID).StunApplied++
-					eid := eff.EffectID
-					combatLog = append(combatLog, CombatLogEntry{Turn: 0, CharacterID: char.CharacterID, Action: "stun", Factor: 0, EffectID: &eid, TriggerType: "on_start"})
-				}
-			case "modify_damage", "modify_dodge", "modify_crit", "modify_armor", "modify_heal",
-				"counterattack", "double_attack", "consecutive_damage":
-				if eff.TargetSelf {
-					applyModifier(0, char.CharacterID, charMods, charBuffs, &eff, "on_start")
-				} else {
-					applyModifier(0, char.CharacterID, opponentMods, opponentBuffs, &eff, "on_start")
+			var targets []*combatant
+			for _, target := range e.teams[team] {
+				if target.hp > 0 && e.conditionHolds(eff, trigger, owner, opponent, target) {
+					targets = append(targets, target)
 				}
 			}
+			// One proc covers every target
+			if len(targets) == 0 || !e.tryProc(owner, i) {
+				continue
+			}
+			e.notifyProc(owner, eff, trigger, targets...)
+			for _, target := range targets {
+				e.apply(h, effectContext{effect: eff, trigger: trigger, source: owner, target: target, damage: damage})
+			}
+			continue
 		}
-	}
-	fireStartEffects(player, &playerCurrentHP, playerMaxHP, &playerMods, &playerTempBuffs,
-		&enemyCurrentHP, enemyMaxHP, &enemyMods, &enemyTempBuffs, &enemyBleedStacks, &enemyStunned)
-	fireStartEffects(enemy, &enemyCurrentHP, enemyMaxHP, &enemyMods, &enemyTempBuffs,
-		&playerCurrentHP, playerMaxHP, &playerMods, &playerTempBuffs, &playerBleedStacks, &playerStunned)
-
-	calculateDamage := func(attacker *CombatCharacter, attackerMods *CombatModifiers) int {
-		damageRange := attacker.MaxDamage - attacker.MinDamage
-		baseDamage := attacker.MinDamage
-		if damageRange > 0 {
-			baseDamage += rand.Intn(damageRange + 1)
-		}
-		finalDamage := baseDamage + attacker.Strength
-		if attackerMods.DamageModifier != 0 {
-			finalDamage = finalDamage + (finalDamage * attackerMods.DamageModifier / 100)
+		target := opponent
+		if eff.TargetSelf {
+			target = owner
 		}
-		if finalDamage < 0 {
-			finalDamage = 0
+		if !e.conditionHolds(eff, trigger, owner, opponent, target) || !e.tryProc(owner, i) {
+			continue
 		}
-		return finalDamage
+		e.notifyProc(owner, eff, trigger, target)
+		e.apply(h, effectContext{effect: eff, trigger: trigger, source: owner, target: target, damage: damage})
 	}
+}
 
-	applyArmor := func(damage int, defender *CombatCharacter, defenderMods *CombatModifiers) int {
-		armor := defender.Armor
-		if defenderMods.ArmorModifier != 0 {
-			armor = armor + (armor * defenderMods.ArmorModifier / 100)
-		}
-		if armor <= 0 {
-			return damage
+// apply runs a handler on the engine's scratch context, saving an allocation
+// per proc. Handlers never fire other effects, so one context is enough.
+func (e *combatEngine) apply(h *effectHandler, ctx effectContext) {
+	e.ctx = ctx
+	h.apply(e, &e.ctx)
+}
+
+func (e *combatEngine) conditionHolds(eff *CombatTestEffect, trigger string, owner *combatant, opponent *combatant, target *combatant) bool {
+	return checkCondition(eff, &conditionContext{owner: owner, opponent: opponent, target: target, trigger: trigger, turn: e.turn})
+}
+
+// dealDamage removes HP from target and books it in both sides' stats.
+// target's shields soak up what they can first; the absorbed part is
+// returned for the caller's log entry and doesn't count as damage dealt.
+// source is who dealt it and effectID the effect behind it (0 for attacks);
+// DoT ticks (cause bleed, poison or burn) are reported to observers as the
+// applier's but not booked as damage dealt.
+func (e *combatEngine) dealDamage(source *combatant, target *combatant, amount int, cause string, effectID int) int {
+	absorbed := 0
+	if amount > 0 {
+		absorbed = e.absorbDamage(target, amount)
+	}
+	hpDamage := amount - absorbed
+	wasAlive := target.hp > 0
+	target.hp -= hpDamage
+	e.notifyDamage(source, target, hpDamage, absorbed, cause, effectID)
+	if wasAlive && target.hp <= 0 {
+		e.notifyDeath(source, target, cause)
+	}
+	if hpDamage <= 0 {
+		return absorbed
+	}
+	target.stats.DamageTaken += hpDamage
+	if source != nil && source != target && !isDotKind(cause) {
+		source.stats.DamageDealt += hpDamage
+	}
+	return absorbed
+}
+
+// absorbDamage drains c's shield layers by up to amount and returns how much
+// they absorbed. Each layer that runs dry is logged as shield_break.
+func (e *combatEngine) absorbDamage(c *combatant, amount int) int {
+	absorbed := 0
+	for len(c.shields) > 0 && absorbed < amount {
+		layer := &c.shields[0]
+		take := amount - absorbed
+		if take > layer.points {
+			take = layer.points
+		}
+		layer.points -= take
+		absorbed += take
+		if layer.points > 0 {
+			break
 		}
-		reduced := damage * 100 / (armor + 100)
-		if reduced < 1 {
-			reduced = 1
+		if e.logging {
+			eid := layer.effectID
+			e.emit(CombatLogEntry{Turn: e.turn, CharacterID: c.CharacterID, Action: "shield_break", Factor: 0, EffectID: &eid, BuffType: "shield"})
 		}
-		return reduced
+		e.notifyBuff(c, "shield", layer.effectID, 0, buffRemoved)
+		c.shields = c.shields[1:]
 	}
+	c.stats.ShieldAbsorbed += absorbed
+	return absorbed
+}
 
-	executeTurn := func(turn int,
-		attacker *CombatCharacter, attackerMods *CombatModifiers, attackerHP *int, attackerMaxHP int, attackerStunned *bool, attackerBleed *int, attackerConsecHits *int, attackerBuffs *[]TempBuff,
-		defender *CombatCharacter, defenderMods *CombatModifiers, defenderHP *int, defenderMaxHP int, defenderStunned *bool, defenderBleed *int, defenderConsecHits *int, defenderBuffs *[]TempBuff,
-	) {
-		aStats := getStats(attacker.CharacterID)
+func (e *combatEngine) calculateDamage(attacker *combatant) int {
+	damageRange := attacker.MaxDamage - attacker.MinDamage
+	baseDamage := attacker.MinDamage
+	if damageRange > 0 {
+		baseDamage += e.rng.Intn(damageRange + 1)
+	}
+	finalDamage := baseDamage + attacker.Strength
+	if attacker.mods.DamageModifier != 0 {
+		finalDamage = finalDamage + (finalDamage * attacker.mods.DamageModifier / 100)
+	}
+	if finalDamage < 0 {
+		finalDamage = 0
+	}
+	return finalDamage
+}
 
-		// on_turn_start effects
-		for _, eff := range collectTriggeredEffects(attacker, "on_turn_start") {
-			if !checkCondition(&eff, *attackerHP, attackerMaxHP) {
-				continue
-			}
-			switch eff.CoreEffectCode {
-			case "damage":
-				val := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, 0)
-				if eff.TargetSelf {
-					*attackerHP -= val
-				} else {
-					*defenderHP -= val
-					if val > 0 {
-						aStats.DamageDealt += val
-						getStats(defender.CharacterID).DamageTaken += val
-					}
-				}
-				combatLog = append(combatLog, logDamageEntry(turn, attacker.CharacterID, &eff, val, "on_turn_start"))
-			case "heal":
-				val := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, 0)
-				if eff.TargetSelf {
-					*attackerHP += val
-					if val > 0 {
-						aStats.HealingDone += val
-					}
-				} else {
-					*defenderHP += val
-				}
-				combatLog = append(combatLog, logHealEntry(turn, attacker.CharacterID, &eff, val, "on_turn_start"))
-			case "modify_damage", "modify_dodge", "modify_crit", "modify_armor", "modify_heal",
-				"counterattack", "double_attack", "consecutive_damage":
-				if eff.TargetSelf {
-					applyModifier(turn, attacker.CharacterID, attackerMods, attackerBuffs, &eff, "on_turn_start")
-				} else {
-					applyModifier(turn, defender.CharacterID, defenderMods, defenderBuffs, &eff, "on_turn_start")
-				}
-			}
+// applyArmor mitigates damage by the defender's armor, of which the
+// attacker's armor penetration (percent) ignores a share
+func (e *combatEngine) applyArmor(damage int, defender *combatant, penetration int) int {
+	armor := defender.Armor
+	if defender.mods.ArmorModifier != 0 {
+		armor = armor + (armor * defender.mods.ArmorModifier / 100)
+	}
+	if penetration > 0 {
+		if penetration > 100 {
+			penetration = 100
 		}
+		armor -= armor * penetration / 100
+	}
+	return e.rules.mitigate(damage, armor)
+}
 
-		// on_every_other_turn effects
-		if turn%2 == 1 {
-			for _, eff := range collectTriggeredEffects(attacker, "on_every_other_turn") {
-				if !checkCondition(&eff, *attackerHP, attackerMaxHP) {
-					continue
-				}
-				switch eff.CoreEffectCode {
-				case "damage":
-					val := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, 0)
-					if eff.TargetSelf {
-						*attackerHP -= val
-					} else {
-						*defenderHP -= val
-						if val > 0 {
-							aStats.DamageDealt += val
-							getStats(defender.CharacterID).DamageTaken += val
-						}
-					}
-					combatLog = append(combatLog, logDamageEntry(turn, attacker.CharacterID, &eff, val, "on_every_other_turn"))
-				case "heal":
-					val := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, 0)
-					if eff.TargetSelf {
-						*attackerHP += val
-						if val > 0 {
-							aStats.HealingDone += val
-						}
-					} else {
-						*defenderHP += val
-					}
-					combatLog = append(combatLog, logHealEntry(turn, attacker.CharacterID, &eff, val, "on_every_other_turn"))
-				case "modify_damage", "modify_dodge", "modify_crit", "modify_armor", "modify_heal",
-					"counterattack", "double_attack", "consecutive_damage":
-					if eff.TargetSelf {
-						applyModifier(turn, attacker.CharacterID, attackerMods, attackerBuffs, &eff, "on_every_other_turn")
-					} else {
-						applyModifier(turn, defender.CharacterID, defenderMods, defenderBuffs, &eff, "on_every_other_turn")
-					}
-				}
-			}
-		}
+func (e *combatEngine) executeTurn(attacker *combatant, defender *combatant) {
+	e.notifyTurnStart(attacker)
+	e.fire("on_turn_start", attacker, defender, 0)
+	if e.turn%2 == 1 {
+		e.fire("on_every_other_turn", attacker, defender, 0)
+	}
 
-		// Bleed damage at start of turn
-		if *attackerBleed > 0 {
-			bleedDmg := *attackerBleed
-			*attackerHP -= bleedDmg
-			aStats.DamageTaken += bleedDmg
-			combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: attacker.CharacterID, Action: "bleed", Factor: bleedDmg})
-		}
+	// DoT damage at start of turn; a fighter its DoTs kill doesn't act
+	e.tickDots(attacker)
+	if attacker.hp > 0 {
+		e.act(attacker, defender)
+	}
 
-		// Stun check — character IS stunned, skips turn
-		if *attackerStunned {
-			*attackerStunned = false
-			*attackerConsecHits = 0 // stun breaks consecutive hits
-			aStats.TimesStunned++
-			combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: attacker.CharacterID, Action: "stunned", Factor: 0})
-			goto turnEnd
+	// Clamp HP
+	for _, c := range e.fighters {
+		if c.hp > c.maxHP {
+			c.hp = c.maxHP
 		}
+	}
+	e.notifySnapshot(attacker)
+}
 
-		// ── Perform attack (and potentially double attack) ──
-		{
-			// Helper: perform one full attack sequence (dodge check → damage → crit → armor → on_hit → on_crit → on_crit_taken → on_hit_taken → counter)
-			performAttack := func(isDoubleAttack bool) {
-				// Dodge check — based on defender agility vs attacker agility
-				dodgeChance := statBasedChance(defender.Agility, attacker.Agility, defenderMods.DodgeChance)
-				if rand.Intn(100) < dodgeChance {
-					*attackerConsecHits = 0 // dodge breaks consecutive hits
-					aStats.AttacksDodged++
-					getStats(defender.CharacterID).DodgedAttacks++
-					combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: attacker.CharacterID, Action: "dodge", Factor: 0})
-					return
-				}
+// act plays the rest of a living fighter's turn: its attacks unless it is
+// stunned, then on_turn_end and its buffs ticking down
+func (e *combatEngine) act(attacker *combatant, defender *combatant) {
+	// Stun check — character IS stunned, skips its attack
+	stunned := e.loseStunnedTurn(attacker)
+	if !stunned {
+		// Primary attack
+		e.performAttack(attacker, defender, false)
 
-				// Consecutive hit tracking
-				*attackerConsecHits++
-				if *attackerConsecHits > aStats.MaxConsecHits {
-					aStats.MaxConsecHits = *attackerConsecHits
-				}
+		// Double attack check — if triggered, perform a full second attack
+		if attacker.mods.DoubleAttackChance > 0 && e.rng.Intn(100) < attacker.mods.DoubleAttackChance {
+			attacker.stats.DoubleAttacks++
+			e.performAttack(attacker, defender, true)
+		}
+	}
 
-				// Calculate base damage
-				damage := calculateDamage(attacker, attackerMods)
+	e.fire("on_turn_end", attacker, defender, 0)
+	e.tickBuffs(attacker)
+	if !stunned {
+		e.tickStunImmunity(attacker)
+	}
+}
 
-				// Apply consecutive damage bonus (% increase per hit in streak)
-				if attackerMods.ConsecutiveDamageBonus > 0 && *attackerConsecHits > 1 {
-					bonus := attackerMods.ConsecutiveDamageBonus * (*attackerConsecHits - 1)
-					damage = damage + (damage * bonus / 100)
-				}
+// performAttack runs one full attack sequence: dodge check → damage → crit →
+// armor → on_hit → on_crit → on_hit_taken → on_crit_taken → counter
+func (e *combatEngine) performAttack(attacker *combatant, defender *combatant, isDoubleAttack bool) {
+	attacker.attacksStarted++
+
+	// Dodge check — based on defender agility vs attacker agility
+	dodgeChance := e.rules.chance(defender.Agility, attacker.Agility, defender.mods.DodgeChance)
+	if e.rng.Intn(100) < dodgeChance {
+		attacker.consecHits = 0 // dodge breaks consecutive hits
+		attacker.stats.AttacksDodged++
+		defender.stats.DodgedAttacks++
+		if e.logging {
+			e.emit(CombatLogEntry{Turn: e.turn, CharacterID: attacker.CharacterID, TargetID: defender.CharacterID, Action: "dodge", Factor: 0})
+		}
+		e.notifyAttack(AttackEvent{AttackerID: attacker.CharacterID, DefenderID: defender.CharacterID, Dodged: true, Double: isDoubleAttack})
+		attacker.lastAttackDodged = true
+		return
+	}
 
-				// Crit check — based on attacker luck vs defender luck
-				isCrit := false
-				critChance := statBasedChance(attacker.Luck, defender.Luck, attackerMods.CritChance)
-				if rand.Intn(100) < critChance {
-					isCrit = true
-					damage = damage * 2
-					aStats.CritHits++
-				}
+	// Consecutive hit tracking
+	attacker.consecHits++
+	if attacker.consecHits > attacker.stats.MaxConsecHits {
+		attacker.stats.MaxConsecHits = attacker.consecHits
+	}
 
-				// Apply armor
-				damage = applyArmor(damage, defender, defenderMods)
+	damage := e.calculateDamage(attacker)
 
-				// Apply damage
-				*defenderHP -= damage
-				aStats.DamageDealt += damage
-				aStats.Attacks++
-				getStats(defender.CharacterID).DamageTaken += damage
+	// Apply consecutive damage bonus (% increase per hit in streak)
+	if attacker.mods.ConsecutiveDamageBonus > 0 && attacker.consecHits > 1 {
+		bonus := attacker.mods.ConsecutiveDamageBonus * (attacker.consecHits - 1)
+		damage = damage + (damage * bonus / 100)
+	}
 
-				action := "attack"
-				if isCrit {
-					action = "crit"
-				}
-				combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: attacker.CharacterID, Action: action, Factor: damage})
-
-				// on_hit effects
-				for _, eff := range collectTriggeredEffects(attacker, "on_hit") {
-					condHP, condMaxHP := *attackerHP, attackerMaxHP
-					if !eff.TargetSelf {
-						condHP, condMaxHP = *defenderHP, defenderMaxHP
-					}
-					if !checkCondition(&eff, condHP, condMaxHP) {
-						continue
-					}
-					switch eff.CoreEffectCode {
-					case "damage":
-						val := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, damage)
-						if eff.TargetSelf {
-							*attackerHP -= val
-						} else {
-							*defenderHP -= val
-							if val > 0 {
-								aStats.DamageDealt += val
-								getStats(defender.CharacterID).DamageTaken += val
-							}
-						}
-						combatLog = append(combatLog, logDamageEntry(turn, attacker.CharacterID, &eff, val, "on_hit"))
-					case "heal":
-						val := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, damage)
-						if eff.TargetSelf {
-							*attackerHP += val
-							if val > 0 {
-								aStats.HealingDone += val
-							}
-						} else {
-							*defenderHP += val
-						}
-						combatLog = append(combatLog, logHealEntry(turn, attacker.CharacterID, &eff, val, "on_hit"))
-					case "stun":
-						if rand.Intn(100) < eff.Value {
-							*defenderStunned = true
-							aStats.StunApplied++
-							eid := eff.EffectID
-							combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: attacker.CharacterID, Action: "stun", Factor: 0, EffectID: &eid, TriggerType: "on_hit"})
-						}
-					case "bleed":
-						bleedAmount := resolveFactorValue(&eff, defenderMaxHP, *defenderHP, damage)
-						*defenderBleed += bleedAmount
-						aStats.BleedApplied += bleedAmount
-						eid := eff.EffectID
-						combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: attacker.CharacterID, Action: "bleed", Factor: bleedAmount, EffectID: &eid, TriggerType: "on_hit"})
-					case "modify_damage", "modify_dodge", "modify_crit", "modify_armor", "modify_heal",
-						"counterattack", "double_attack", "consecutive_damage":
-						if eff.TargetSelf {
-							applyModifier(turn, attacker.CharacterID, attackerMods, attackerBuffs, &eff, "on_hit")
-						} else {
-							applyModifier(turn, defender.CharacterID, defenderMods, defenderBuffs, &eff, "on_hit")
-						}
-					}
-				}
+	// Crit check — based on attacker luck vs defender luck
+	isCrit := false
+	critChance := e.rules.chance(attacker.Luck, defender.Luck, attacker.mods.CritChance)
+	if e.rng.Intn(100) < critChance {
+		isCrit = true
+		damage = damage * e.rules.CritMultiplier / 100
+		attacker.stats.CritHits++
+	}
 
-				// on_crit effects
-				if isCrit {
-					for _, eff := range collectTriggeredEffects(attacker, "on_crit") {
-						condHP, condMaxHP := *attackerHP, attackerMaxHP
-						if !eff.TargetSelf {
-							condHP, condMaxHP = *defenderHP, defenderMaxHP
-						}
-						if !checkCondition(&eff, condHP, condMaxHP) {
-							continue
-						}
-						switch eff.CoreEffectCode {
-						case "damage":
-							val := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, damage)
-							if eff.TargetSelf {
-								*attackerHP -= val
-							} else {
-								*defenderHP -= val
-								if val > 0 {
-									aStats.DamageDealt += val
-									getStats(defender.CharacterID).DamageTaken += val
-								}
-							}
-							combatLog = append(combatLog, logDamageEntry(turn, attacker.CharacterID, &eff, val, "on_crit"))
-						case "heal":
-							val := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, damage)
-							if eff.TargetSelf {
-								*attackerHP += val
-								if val > 0 {
-									aStats.HealingDone += val
-								}
-							} else {
-								*defenderHP += val
-							}
-							combatLog = append(combatLog, logHealEntry(turn, attacker.CharacterID, &eff, val, "on_crit"))
-						case "stun":
-							if rand.Intn(100) < eff.Value {
-								*defenderStunned = true
-								aStats.StunApplied++
-								eid := eff.EffectID
-								combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: attacker.CharacterID, Action: "stun", Factor: 0, EffectID: &eid, TriggerType: "on_crit"})
-							}
-						case "bleed":
-							bleedAmount := resolveFactorValue(&eff, defenderMaxHP, *defenderHP, damage)
-							*defenderBleed += bleedAmount
-							aStats.BleedApplied += bleedAmount
-							eid := eff.EffectID
-							combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: attacker.CharacterID, Action: "bleed", Factor: bleedAmount, EffectID: &eid, TriggerType: "on_crit"})
-						case "modify_damage", "modify_dodge", "modify_crit", "modify_armor", "modify_heal",
-							"counterattack", "double_attack", "consecutive_damage":
-							if eff.TargetSelf {
-								applyModifier(turn, attacker.CharacterID, attackerMods, attackerBuffs, &eff, "on_crit")
-							} else {
-								applyModifier(turn, defender.CharacterID, defenderMods, defenderBuffs, &eff, "on_crit")
-							}
-						}
-					}
-				}
+	damage = e.mitigateHit(damage, attacker, defender)
+	action := "attack"
+	if isCrit {
+		action = "crit"
+	}
+	e.notifyAttack(AttackEvent{AttackerID: attacker.CharacterID, DefenderID: defender.CharacterID, Crit: isCrit, Double: isDoubleAttack, Damage: damage})
+	absorbed := e.dealDamage(attacker, defender, damage, action, 0)
+	attacker.stats.Attacks++
 
-				// on_hit_taken effects (defender reacts)
-				for _, eff := range collectTriggeredEffects(defender, "on_hit_taken") {
-					if !checkCondition(&eff, *defenderHP, defenderMaxHP) {
-						continue
-					}
-					switch eff.CoreEffectCode {
-					case "damage":
-						val := resolveFactorValue(&eff, defenderMaxHP, *defenderHP, damage)
-						if eff.TargetSelf {
-							*defenderHP -= val
-						} else {
-							*attackerHP -= val
-							if val > 0 {
-								getStats(defender.CharacterID).DamageDealt += val
-								aStats.DamageTaken += val
-							}
-						}
-						combatLog = append(combatLog, logDamageEntry(turn, defender.CharacterID, &eff, val, "on_hit_taken"))
-					case "heal":
-						val := resolveFactorValue(&eff, defenderMaxHP, *defenderHP, damage)
-						if eff.TargetSelf {
-							*defenderHP += val
-							if val > 0 {
-								getStats(defender.CharacterID).HealingDone += val
-							}
-						} else {
-							*attackerHP += val
-						}
-						combatLog = append(combatLog, logHealEntry(turn, defender.CharacterID, &eff, val, "on_hit_taken"))
-					case "modify_damage", "modify_dodge", "modify_crit", "modify_armor", "modify_heal",
-						"counterattack", "double_attack", "consecutive_damage":
-						if eff.TargetSelf {
-							applyModifier(turn, defender.CharacterID, defenderMods, defenderBuffs, &eff, "on_hit_taken")
-						} else {
-							applyModifier(turn, attacker.CharacterID, attackerMods, attackerBuffs, &eff, "on_hit_taken")
-						}
-					case "bleed":
-						bleedAmount := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, damage)
-						*attackerBleed += bleedAmount
-						getStats(defender.CharacterID).BleedApplied += bleedAmount
-						eid := eff.EffectID
-						combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: defender.CharacterID, Action: "bleed", Factor: bleedAmount, EffectID: &eid, TriggerType: "on_hit_taken"})
-					}
-				}
+	if e.logging {
+		e.emit(CombatLogEntry{Turn: e.turn, CharacterID: attacker.CharacterID, TargetID: defender.CharacterID, Action: action, Factor: damage, Absorbed: absorbed})
+	}
+	e.afterHit(attacker, defender, damage, absorbed)
 
-				// on_crit_taken effects (defender reacts to being crit)
-				if isCrit {
-					for _, eff := range collectTriggeredEffects(defender, "on_crit_taken") {
-						if !checkCondition(&eff, *defenderHP, defenderMaxHP) {
-							continue
-						}
-						switch eff.CoreEffectCode {
-						case "damage":
-							val := resolveFactorValue(&eff, defenderMaxHP, *defenderHP, damage)
-							if eff.TargetSelf {
-								*defenderHP -= val
-							} else {
-								*attackerHP -= val
-								if val > 0 {
-									getStats(defender.CharacterID).DamageDealt += val
-									aStats.DamageTaken += val
-								}
-							}
-							combatLog = append(combatLog, logDamageEntry(turn, defender.CharacterID, &eff, val, "on_crit_taken"))
-						case "heal":
-							val := resolveFactorValue(&eff, defenderMaxHP, *defenderHP, damage)
-							if eff.TargetSelf {
-								*defenderHP += val
-								if val > 0 {
-									getStats(defender.CharacterID).HealingDone += val
-								}
-							} else {
-								*attackerHP += val
-							}
-							combatLog = append(combatLog, logHealEntry(turn, defender.CharacterID, &eff, val, "on_crit_taken"))
-						case "bleed":
-							bleedAmount := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, damage)
-							*attackerBleed += bleedAmount
-							getStats(defender.CharacterID).BleedApplied += bleedAmount
-							eid := eff.EffectID
-							combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: defender.CharacterID, Action: "bleed", Factor: bleedAmount, EffectID: &eid, TriggerType: "on_crit_taken"})
-						case "stun":
-							if rand.Intn(100) < eff.Value {
-								*attackerStunned = true
-								getStats(defender.CharacterID).StunApplied++
-								eid := eff.EffectID
-								combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: defender.CharacterID, Action: "stun", Factor: 0, EffectID: &eid, TriggerType: "on_crit_taken"})
-							}
-						case "modify_damage", "modify_dodge", "modify_crit", "modify_armor", "modify_heal",
-							"counterattack", "double_attack", "consecutive_damage":
-							if eff.TargetSelf {
-								applyModifier(turn, defender.CharacterID, defenderMods, defenderBuffs, &eff, "on_crit_taken")
-							} else {
-								applyModifier(turn, attacker.CharacterID, attackerMods, attackerBuffs, &eff, "on_crit_taken")
-							}
-						}
-					}
-				}
+	e.fire("on_hit", attacker, defender, damage)
+	if isCrit {
+		e.fire("on_crit", attacker, defender, damage)
+	}
+	e.fire("on_hit_taken", defender, attacker, damage)
+	if isCrit {
+		e.fire("on_crit_taken", defender, attacker, damage)
+	}
 
-				// Counterattack check (only on first/main attack, not double)
-				if !isDoubleAttack && defenderMods.CounterChance > 0 && rand.Intn(100) < defenderMods.CounterChance {
-					counterDmg := calculateDamage(defender, defenderMods)
-					counterDmg = applyArmor(counterDmg, attacker, attackerMods)
-					*attackerHP -= counterDmg
-					getStats(defender.CharacterID).DamageDealt += counterDmg
-					getStats(defender.CharacterID).CounterHits++
-					aStats.DamageTaken += counterDmg
-					combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: defender.CharacterID, Action: "counterattack", Factor: counterDmg})
-				}
-			}
+	// Counterattack check (only on first/main attack, not double)
+	if !isDoubleAttack && defender.mods.CounterChance > 0 && e.rng.Intn(100) < defender.mods.CounterChance {
+		counterDmg := e.mitigateHit(e.calculateDamage(defender), defender, attacker)
+		e.notifyAttack(AttackEvent{AttackerID: defender.CharacterID, DefenderID: attacker.CharacterID, Counter: true, Damage: counterDmg})
+		counterAbsorbed := e.dealDamage(defender, attacker, counterDmg, "counterattack", 0)
+		defender.stats.CounterHits++
+		if e.logging {
+			e.emit(CombatLogEntry{Turn: e.turn, CharacterID: defender.CharacterID, TargetID: attacker.CharacterID, Action: "counterattack", Factor: counterDmg, Absorbed: counterAbsorbed})
+		}
+		e.afterHit(defender, attacker, counterDmg, counterAbsorbed)
+	}
 
-			// Primary attack
-			performAttack(false)
+	// Cleared only now so this hit's triggers still see the previous attack's outcome
+	attacker.lastAttackDodged = false
+}
 
-			// Double attack check — if triggered, perform a full second attack
-			if attackerMods.DoubleAttackChance > 0 && rand.Intn(100) < attackerMods.DoubleAttackChance {
-				aStats.DoubleAttacks++
-				performAttack(true)
-			}
+// tickBuffs counts down c's temp buffs and timed shields at the end of its
+// turn, reverting expired buffs and dropping expired shields
+func (e *combatEngine) tickBuffs(c *combatant) {
+	remaining := c.buffs[:0]
+	for _, b := range c.buffs {
+		b.Remaining--
+		if b.Remaining > 0 {
+			remaining = append(remaining, b)
+			continue
 		}
-
-	turnEnd:
-		// on_turn_end effects
-		for _, eff := range collectTriggeredEffects(attacker, "on_turn_end") {
-			if !checkCondition(&eff, *attackerHP, attackerMaxHP) {
-				continue
-			}
-			switch eff.CoreEffectCode {
-			case "damage":
-				val := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, 0)
-				if eff.TargetSelf {
-					*attackerHP -= val
-				} else {
-					*defenderHP -= val
-					if val > 0 {
-						aStats.DamageDealt += val
-						getStats(defender.CharacterID).DamageTaken += val
-					}
-				}
-				combatLog = append(combatLog, logDamageEntry(turn, attacker.CharacterID, &eff, val, "on_turn_end"))
-			case "heal":
-				val := resolveFactorValue(&eff, attackerMaxHP, *attackerHP, 0)
-				if eff.TargetSelf {
-					*attackerHP += val
-					if val > 0 {
-						aStats.HealingDone += val
-					}
-				} else {
-					*defenderHP += val
-				}
-				combatLog = append(combatLog, logHealEntry(turn, attacker.CharacterID, &eff, val, "on_turn_end"))
-			case "bleed":
-				bleedAmount := resolveFactorValue(&eff, defenderMaxHP, *defenderHP, 0)
-				*defenderBleed += bleedAmount
-				aStats.BleedApplied += bleedAmount
-				eid := eff.EffectID
-				combatLog = append(combatLog, CombatLogEntry{Turn: turn, CharacterID: attacker.CharacterID, Action: "bleed", Factor: bleedAmount, EffectID: &eid, TriggerType: "on_turn_end"})
-			case "modify_damage", "modify_dodge", "modify_crit", "modify_armor", "modify_heal",
-				"counterattack", "double_attack", "consecutive_damage":
-				if eff.TargetSelf {
-					applyModifier(turn, attacker.CharacterID, attackerMods, attackerBuffs, &eff, "on_turn_end")
-				} else {
-					applyModifier(turn, defender.CharacterID, defenderMods, defenderBuffs, &eff, "on_turn_end")
-				}
-			}
+		if field := modifierField(&c.mods, b.ModifierType); field != nil {
+			*field -= b.Value
 		}
-
-		// Tick down temp buffs on attacker and remove expired ones
-		{
-			remaining := (*attackerBuffs)[:0]
-			for _, b := range *attackerBuffs {
-				b.Remaining--
-				if b.Remaining <= 0 {
-					// Remove the modifier
-					switch b.ModifierType {
-					case "modify_damage":
-						attackerMods.DamageModifier -= b.Value
-					case "modify_dodge":
-						attackerMods.DodgeChance -= b.Value
-					case "modify_crit":
-						attackerMods.CritChance -= b.Value
-					case "modify_armor":
-						attackerMods.ArmorModifier -= b.Value
-					case "modify_heal":
-						attackerMods.HealModifier -= b.Value
-					case "counterattack":
-						attackerMods.CounterChance -= b.Value
-					case "double_attack":
-						attackerMods.DoubleAttackChance -= b.Value
-					case "consecutive_damage":
-						attackerMods.ConsecutiveDamageBonus -= b.Value
-					}
-					eid := b.EffectID
-					combatLog = append(combatLog, CombatLogEntry{
-						Turn: turn, CharacterID: attacker.CharacterID, Action: "buff_expire",
-						Factor: b.Value, EffectID: &eid, BuffType: b.ModifierType,
+		if e.logging {
+			eid := b.EffectID
+			e.emit(CombatLogEntry{
+				Turn: e.turn, CharacterID: c.CharacterID, Action: "buff_expire",
+				Factor: b.Value, EffectID: &eid, BuffType: b.ModifierType,
+			})
+		}
+		e.notifyBuff(c, b.ModifierType, b.EffectID, b.Value, buffExpired)
+	}
+	c.buffs = remaining
+
+	shields := c.shields[:0]
+	for _, l := range c.shields {
+		if l.remaining > 0 {
+			l.remaining--
+			if l.remaining == 0 {
+				if e.logging {
+					eid := l.effectID
+					e.emit(CombatLogEntry{
+						Turn: e.turn, CharacterID: c.CharacterID, Action: "buff_expire",
+						Factor: l.points, EffectID: &eid, BuffType: "shield",
 					})
-				} else {
-					remaining = append(remaining, b)
 				}
+				e.notifyBuff(c, "shield", l.effectID, l.points, buffExpired)
+				continue
 			}
-			*attackerBuffs = remaining
-		}
-
-		// Clamp HP
-		if *attackerHP > attackerMaxHP {
-			*attackerHP = attackerMaxHP
-		}
-		if *defenderHP > defenderMaxHP {
-			*defenderHP = defenderMaxHP
 		}
+		shields = append(shields, l)
 	}
+	c.shields = shields
+}
 
-	// Combat loop
-	for turn := 1; turn <= maxTurns; turn++ {
-		executeTurn(turn,
-			player, &playerMods, &playerCurrentHP, playerMaxHP, &playerStunned, &playerBleedStacks, &playerConsecutiveHits, &playerTempBuffs,
-			enemy, &enemyMods, &enemyCurrentHP, enemyMaxHP, &enemyStunned, &enemyBleedStacks, &enemyConsecutiveHits, &enemyTempBuffs,
-		)
-		if enemyCurrentHP <= 0 {
-			winnerID = player.CharacterID
-			break
-		}
-		if playerCurrentHP <= 0 {
-			winnerID = enemy.CharacterID
-			break
-		}
+// maxCombatSeed keeps generated seeds within 2^53 so they survive a round trip
+// through JavaScript numbers in the combat tester.
+const maxCombatSeed = 1 << 53
 
-		executeTurn(turn,
-			enemy, &enemyMods, &enemyCurrentHP, enemyMaxHP, &enemyStunned, &enemyBleedStacks, &enemyConsecutiveHits, &enemyTempBuffs,
-			player, &playerMods, &playerCurrentHP, playerMaxHP, &playerStunned, &playerBleedStacks, &playerConsecutiveHits, &playerTempBuffs,
-		)
-		if playerCurrentHP <= 0 {
-			winnerID = enemy.CharacterID
-			break
-		}
-		if enemyCurrentHP <= 0 {
-			winnerID = player.CharacterID
-			break
-		}
-	}
+// newCombatSeed picks a random seed for a fight that didn't supply one
+func newCombatSeed() int64 {
+	return rand.Int63n(maxCombatSeed)
+}
 
-	// Timeout: highest HP wins
-	if winnerID == 0 {
-		if playerCurrentHP >= enemyCurrentHP {
-			winnerID = player.CharacterID
-		} else {
-			winnerID = enemy.CharacterID
-		}
-	}
+// executeCombat runs one fight with a freshly generated seed
+func executeCombat(player *CombatCharacter, enemy *CombatCharacter) *CombatResult {
+	return executeCombatSeeded(player, enemy, newCombatSeed())
+}
 
-	playerHPEnd := playerCurrentHP
-	if playerHPEnd < 0 {
-		playerHPEnd = 0
-	}
-	enemyHPEnd := enemyCurrentHP
-	if enemyHPEnd < 0 {
-		enemyHPEnd = 0
-	}
-
-	return map[string]interface{}{
-		"header": map[string]interface{}{
-			"winnerId": winnerID,
-			"combatant1": map[string]interface{}{
-				"id":    player.CharacterID,
-				"name":  player.CharacterName,
-				"maxHp": playerMaxHP,
-				"hpEnd": playerHPEnd,
-			},
-			"combatant2": map[string]interface{}{
-				"id":    enemy.CharacterID,
-				"name":  enemy.CharacterName,
-				"maxHp": enemyMaxHP,
-				"hpEnd": enemyHPEnd,
-			},
+// executeCombatSeeded runs one fight under the default rules
+func executeCombatSeeded(player *CombatCharacter, enemy *CombatCharacter, seed int64) *CombatResult {
+	return executeCombatWith(player, enemy, CombatOptions{Seed: seed, Rules: defaultCombatRules()})
+}
+
+// executeCombatWith runs one fight drawing every roll from a generator
+// seeded with opts.Seed, so the same inputs, rules and seed reproduce the same log.
+func executeCombatWith(player *CombatCharacter, enemy *CombatCharacter, opts CombatOptions) *CombatResult {
+	party := executePartyCombat(
+		CombatTeam{Members: []*CombatCharacter{player}},
+		CombatTeam{Members: []*CombatCharacter{enemy}},
+		opts,
+	)
+	winnerID := player.CharacterID
+	if party.Header.WinnerTeam == 2 {
+		winnerID = enemy.CharacterID
+	}
+	return &CombatResult{
+		Header: CombatResultHeader{
+			WinnerID:   winnerID,
+			Seed:       party.Header.Seed,
+			Turns:      party.Header.Turns,
+			TimedOut:   party.Header.TimedOut,
+			Combatant1: party.Header.Team1[0],
+			Combatant2: party.Header.Team2[0],
+		},
+		Log: party.Log,
+		Stats: CombatResultStats{
+			Combatant1: party.Stats.Team1[0],
+			Combatant2: party.Stats.Team2[0],
 		},
-		"log": combatLog,
-		"stats": map[string]interface{}{
-			"combatant1": stats1,
-			"combatant2": stats2,
+		Timeline: party.Timeline,
+		Breakdown: CombatResultBreakdown{
+			Combatant1: party.Breakdown.Team1[0],
+			Combatant2: party.Breakdown.Team2[0],
 		},
 	}
 }
+
+func (c *combatant) summary() CombatantSummary {
+	hpEnd := c.hp
+	if hpEnd < 0 {
+		hpEnd = 0
+	}
+	return CombatantSummary{ID: c.CharacterID, Name: c.CharacterName, MaxHP: c.maxHP, HPEnd: hpEnd}
+}
@@ -0,0 +1,134 @@
+package main
+
+import "sort"
+
+// ── Cleanse and dispel ──────────────────────────────────────────────────────
+//
+// cleanse strips debuffs from its target (normally the owner, with
+// targetSelf): temp buffs that hurt the wearer and DoT stacks. dispel strips
+// the temp buffs that help its target (normally the opponent). Value is how
+// many entries go, 0 or less meaning all of them; RemoveOrder picks which go
+// first — the newest applications (default) or the strongest, where strength
+// is a temp buff's |value| or the next tick of a DoT stack. Every removal logs
+// a buff_removed entry on the wearer after the cleanse/dispel entry itself.
+
+// Removal orders for CombatTestEffect.RemoveOrder
+const (
+	removeNewest    = "newest"    // most recently applied first (default)
+	removeStrongest = "strongest" // largest value first, newest first on a tie
+)
+
+var knownRemoveOrders = map[string]bool{"": true, removeNewest: true, removeStrongest: true}
+
+// harmfulModifiers are the modifier codes that hurt the wearer when positive
+var harmfulModifiers = map[string]bool{"anti_heal": true}
+
+// removable is one temp buff or DoT stack a cleanse/dispel can take off
+type removable struct {
+	buff     int // index into buffs, -1 for a DoT stack
+	dot      int // index into dots, -1 for a temp buff
+	applied  int // combatEngine.applied stamp
+	strength int
+}
+
+// isDebuff reports whether a temp buff hurts its wearer
+func (b TempBuff) isDebuff() bool {
+	return (b.Value < 0) != harmfulModifiers[b.ModifierType]
+}
+
+func applyCleanseEffect(e *combatEngine, ctx *effectContext) {
+	var found []removable
+	for i, b := range ctx.target.buffs {
+		if b.isDebuff() {
+			found = append(found, removable{buff: i, dot: -1, applied: b.applied, strength: absInt(b.Value)})
+		}
+	}
+	for i, d := range ctx.target.dots {
+		found = append(found, removable{buff: -1, dot: i, applied: d.applied, strength: d.damage})
+	}
+	removed := e.removeBuffs(ctx, found)
+	ctx.source.stats.DebuffsCleansed += removed
+}
+
+func applyDispelEffect(e *combatEngine, ctx *effectContext) {
+	var found []removable
+	for i, b := range ctx.target.buffs {
+		if !b.isDebuff() {
+			found = append(found, removable{buff: i, dot: -1, applied: b.applied, strength: absInt(b.Value)})
+		}
+	}
+	removed := e.removeBuffs(ctx, found)
+	ctx.source.stats.BuffsDispelled += removed
+}
+
+// removeBuffs takes up to Value of the candidates off the target in the
+// effect's removal order and logs the proc and every removal
+func (e *combatEngine) removeBuffs(ctx *effectContext, found []removable) int {
+	byStrength := ctx.effect.RemoveOrder == removeStrongest
+	sort.SliceStable(found, func(i, j int) bool {
+		if byStrength && found[i].strength != found[j].strength {
+			return found[i].strength > found[j].strength
+		}
+		return found[i].applied > found[j].applied
+	})
+	if n := ctx.effect.Value; n > 0 && n < len(found) {
+		found = found[:n]
+	}
+	if e.logging {
+		e.emit(e.effectLogEntry(ctx, ctx.effect.CoreEffectCode, len(found)))
+	}
+
+	c := ctx.target
+	dropBuff := make([]bool, len(c.buffs))
+	dropDot := make([]bool, len(c.dots))
+	for _, r := range found {
+		var kind string
+		var effectID, factor int
+		if r.buff >= 0 {
+			b := c.buffs[r.buff]
+			if field := modifierField(&c.mods, b.ModifierType); field != nil {
+				*field -= b.Value
+			}
+			dropBuff[r.buff] = true
+			kind, effectID, factor = b.ModifierType, b.EffectID, b.Value
+		} else {
+			d := c.dots[r.dot]
+			dropDot[r.dot] = true
+			kind, effectID, factor = d.kind, d.effectID, d.damage
+		}
+		if e.logging {
+			eid := effectID
+			e.emit(CombatLogEntry{Turn: e.turn, CharacterID: c.CharacterID, Action: "buff_removed", Factor: factor, EffectID: &eid, BuffType: kind})
+		}
+		e.notifyBuff(c, kind, effectID, factor, buffRemoved)
+	}
+
+	buffs := c.buffs[:0]
+	for i, b := range c.buffs {
+		if !dropBuff[i] {
+			buffs = append(buffs, b)
+		}
+	}
+	c.buffs = buffs
+	dots := c.dots[:0]
+	for i, d := range c.dots {
+		if !dropDot[i] {
+			dots = append(dots, d)
+		}
+	}
+	c.dots = dots
+	return len(found)
+}
+
+// nextApplied stamps a new temp buff or DoT stack
+func (e *combatEngine) nextApplied() int {
+	e.applied++
+	return e.applied
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
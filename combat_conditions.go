@@ -0,0 +1,155 @@
+package main
+
+import "fmt"
+
+// ── Effect conditions ───────────────────────────────────────────────────────
+//
+// An effect fires only when its condition holds. The legacy pair
+// ConditionType/ConditionValue (hp_below_percent, hp_above_percent) still
+// works and reads the owner's HP, except on on_hit and on_crit where it reads
+// whoever the effect lands on; Condition is the structured form stored as JSON
+// in game.effects.condition and names its subject explicitly. When both are
+// set both must hold.
+//
+//   {"type": "and", "conditions": [
+//       {"type": "hp_below_percent", "subject": "opponent", "value": 30},
+//       {"type": "or", "conditions": [{"type": "bleeding"}, {"type": "turn_at_least", "value": 5}]}
+//   ]}
+
+// EffectCondition is one node of a structured effect condition
+type EffectCondition struct {
+	Type       string            `json:"type"`                 // and, or, hp_below_percent, hp_above_percent, bleeding, stunned, turn_at_least, first_attack, after_dodged
+	Subject    string            `json:"subject,omitempty"`    // target (default, whoever the effect lands on), self, opponent
+	Value      int               `json:"value,omitempty"`      // threshold for hp_*_percent and turn_at_least
+	Conditions []EffectCondition `json:"conditions,omitempty"` // children of and/or
+}
+
+// conditionTypes lists the leaf types and whether they read a subject
+var conditionTypes = map[string]bool{
+	"hp_below_percent": true,
+	"hp_above_percent": true,
+	"bleeding":         true,
+	"stunned":          true,
+	"turn_at_least":    false, // the fight's turn counter
+	"first_attack":     false, // the owner hasn't started its second attack yet
+	"after_dodged":     false, // the owner's last finished attack was dodged
+}
+
+var conditionSubjects = map[string]bool{"": true, "target": true, "self": true, "opponent": true}
+
+// conditionContext is who an effect's condition can look at when it fires
+type conditionContext struct {
+	owner    *combatant
+	opponent *combatant
+	target   *combatant
+	trigger  string
+	turn     int
+}
+
+// legacySubject is whose HP the legacy condition reads: the target for
+// on_hit and on_crit, the owner for every other trigger
+func (cc *conditionContext) legacySubject() *combatant {
+	if cc.trigger == "on_hit" || cc.trigger == "on_crit" {
+		return cc.target
+	}
+	return cc.owner
+}
+
+// checkCondition reports whether both the legacy condition and the
+// structured condition hold
+func checkCondition(eff *CombatTestEffect, cc *conditionContext) bool {
+	if eff.ConditionType != nil && eff.ConditionValue != nil {
+		subject := cc.legacySubject()
+		pct := subject.hp * 100 / subject.maxHP
+		switch *eff.ConditionType {
+		case "hp_below_percent":
+			if pct >= *eff.ConditionValue {
+				return false
+			}
+		case "hp_above_percent":
+			if pct <= *eff.ConditionValue {
+				return false
+			}
+		}
+	}
+	return eff.Condition == nil || eff.Condition.eval(cc)
+}
+
+// eval evaluates the condition; unknown types pass (the support report flags them)
+func (c *EffectCondition) eval(cc *conditionContext) bool {
+	switch c.Type {
+	case "and":
+		for i := range c.Conditions {
+			if !c.Conditions[i].eval(cc) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for i := range c.Conditions {
+			if c.Conditions[i].eval(cc) {
+				return true
+			}
+		}
+		return len(c.Conditions) == 0
+	case "turn_at_least":
+		return cc.turn >= c.Value
+	case "first_attack":
+		return cc.owner.attacksStarted <= 1
+	case "after_dodged":
+		return cc.owner.lastAttackDodged
+	}
+
+	subject := cc.target
+	switch c.Subject {
+	case "self":
+		subject = cc.owner
+	case "opponent":
+		subject = cc.opponent
+	}
+	if subject == nil {
+		return false
+	}
+	switch c.Type {
+	case "hp_below_percent":
+		return subject.hp*100/subject.maxHP < c.Value
+	case "hp_above_percent":
+		return subject.hp*100/subject.maxHP > c.Value
+	case "bleeding":
+		return subject.hasDot("bleed")
+	case "stunned":
+		return subject.stunTurns > 0
+	}
+	return true
+}
+
+// problems lists what the engine can't evaluate in the condition tree
+func (c *EffectCondition) problems() []string {
+	var out []string
+	switch {
+	case c.Type == "and" || c.Type == "or":
+		if len(c.Conditions) == 0 {
+			out = append(out, c.Type+" without conditions")
+		}
+		for i := range c.Conditions {
+			out = append(out, c.Conditions[i].problems()...)
+		}
+		return out
+	case c.Type == "":
+		out = append(out, "condition without a type always passes")
+	default:
+		usesSubject, ok := conditionTypes[c.Type]
+		if !ok {
+			out = append(out, "unknown condition "+c.Type+" always passes")
+		} else if !usesSubject && c.Subject != "" {
+			out = append(out, fmt.Sprintf("subject %s is ignored by %s", c.Subject, c.Type))
+		}
+	}
+	if !conditionSubjects[c.Subject] {
+		out = append(out, "unknown subject "+c.Subject+" is treated as target")
+	}
+	if len(c.Conditions) > 0 {
+		out = append(out, c.Type+" ignores its child conditions")
+	}
+	return out
+}
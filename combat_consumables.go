@@ -0,0 +1,127 @@
+package main
+
+import "fmt"
+
+// ── Blessings and potions ───────────────────────────────────────────────────
+//
+// Quests reward blessings and potions and settlements grant up to three
+// blessings; a combatant can bring both into a fight. A blessing is a perk
+// with is_blessing set: its effects join the character like a talent perk's.
+// A potion is a game.items row of type potion whose effect becomes a
+// consumable: drunk at the start of the owner's first turn with HP below its
+// threshold (or the first turn at all without one), logged as "potion" and
+// gone after that one proc.
+
+// maxBlessings matches the blessing slots a settlement grants
+const maxBlessings = 3
+
+// CombatPotion is a potion a combatant carries into a fight
+type CombatPotion struct {
+	ItemID         int `json:"itemId"`
+	HPBelowPercent int `json:"hpBelowPercent,omitempty"` // drink once HP drops below this %, 0 = on the first turn
+}
+
+// validateBlessings checks that every ID is a distinct blessing perk
+func validateBlessings(ids []int, perks map[int]Perk) error {
+	if len(ids) > maxBlessings {
+		return fmt.Errorf("at most %d blessings", maxBlessings)
+	}
+	seen := map[int]bool{}
+	for _, id := range ids {
+		p, ok := perks[id]
+		if !ok {
+			return fmt.Errorf("perk %d not found", id)
+		}
+		if !p.IsBlessing {
+			return fmt.Errorf("perk %d (%s) is not a blessing", id, p.Name)
+		}
+		if seen[id] {
+			return fmt.Errorf("blessing %d is listed twice", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// validatePotions checks that every potion is a potion item with an effect
+// and a threshold between 0 and 100
+func validatePotions(potions []CombatPotion, items map[int]Item) error {
+	for _, p := range potions {
+		item, ok := items[p.ItemID]
+		if !ok {
+			return fmt.Errorf("item %d not found", p.ItemID)
+		}
+		if item.Type != "potion" {
+			return fmt.Errorf("item %d (%s) is a %s, not a potion", item.ID, item.Name, item.Type)
+		}
+		if item.EffectID == nil {
+			return fmt.Errorf("potion %d (%s) has no effect", item.ID, item.Name)
+		}
+		if p.HPBelowPercent < 0 || p.HPBelowPercent > 100 {
+			return fmt.Errorf("potion %d: hpBelowPercent must be between 0 and 100", item.ID)
+		}
+	}
+	return nil
+}
+
+// blessCharacter adds the effects of every blessing perk to c. Perks that
+// don't exist or aren't blessings are skipped (validateBlessings reports them).
+func blessCharacter(c *CombatCharacter, ids []int, perks map[int]Perk, effects map[int]Effect) {
+	nextID := ownEffects(c)
+	for _, id := range ids {
+		p, ok := perks[id]
+		if !ok || !p.IsBlessing {
+			continue
+		}
+		for _, pe := range [][2]*int{{p.Effect1ID, p.Factor1}, {p.Effect2ID, p.Factor2}} {
+			if pe[0] == nil || pe[1] == nil {
+				continue
+			}
+			if e, ok := effects[*pe[0]]; ok {
+				c.Effects = append(c.Effects, effectFromTemplate(nextID, e, *pe[1]))
+				nextID++
+			}
+		}
+	}
+}
+
+// stockPotions adds every potion to c as a consumable effect. Potions that
+// don't exist, aren't potions or have no effect are skipped.
+func stockPotions(c *CombatCharacter, potions []CombatPotion, items map[int]Item, effects map[int]Effect) {
+	nextID := ownEffects(c)
+	for _, p := range potions {
+		item, ok := items[p.ItemID]
+		if !ok || item.Type != "potion" || item.EffectID == nil {
+			continue
+		}
+		e, ok := effects[*item.EffectID]
+		if !ok {
+			continue
+		}
+		value := e.Factor
+		if item.EffectFactor != nil {
+			value = *item.EffectFactor
+		}
+		eff := effectFromTemplate(nextID, e, value)
+		eff.TriggerType = "on_turn_start"
+		eff.Consumable = true
+		// The effect's own conditions and proc limits would stop the potion
+		// from being drunk when its owner needs it; only the threshold counts
+		eff.ConditionType, eff.ConditionValue, eff.Condition = nil, nil, nil
+		eff.ProcChance, eff.Cooldown, eff.MaxProcs = nil, 0, 0
+		if p.HPBelowPercent > 0 {
+			eff.Condition = &EffectCondition{Type: "hp_below_percent", Subject: "self", Value: p.HPBelowPercent}
+		}
+		c.Effects = append(c.Effects, eff)
+		nextID++
+	}
+}
+
+// consume logs a consumable's proc; tryProc won't let it proc again
+func (e *combatEngine) consume(c *combatant, eff *CombatTestEffect) {
+	c.stats.PotionsUsed++
+	if e.logging {
+		eid := eff.EffectID
+		e.emit(CombatLogEntry{Turn: e.turn, CharacterID: c.CharacterID, Action: "potion", Factor: eff.Value, EffectID: &eid})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+// ── Damage over time ────────────────────────────────────────────────────────
+//
+// bleed, poison and burn share one subsystem. Every application becomes a
+// dotStack on the victim; stacks tick at the start of the victim's turn with
+// one log entry per DoT type, and drop off once their duration runs out or
+// decay wears them down to nothing.
+
+// dotKinds are the DoT core effect codes in the order they tick
+var dotKinds = []string{"bleed", "poison", "burn"}
+
+// dotIgnoresArmor is each DoT type's armor behaviour when the effect doesn't set ignoreArmor
+var dotIgnoresArmor = map[string]bool{"bleed": true, "poison": true, "burn": false}
+
+// Stacking policies for CombatTestEffect.Stacking
+const (
+	stackingStack   = "stack"      // every application is its own stack (default)
+	stackingRefresh = "refresh"    // one stack per effect; reapplying replaces its damage and duration
+	stackingMax     = "max_stacks" // stacks up to MaxStacks per effect, then replaces the oldest
+)
+
+var knownStackingPolicies = map[string]bool{"": true, stackingStack: true, stackingRefresh: true, stackingMax: true}
+
+// dotStack is one application of a DoT effect
+type dotStack struct {
+	kind        string
+	effectID    int
+	damage      int // damage of the next tick
+	remaining   int // ticks left, 0 = until the fight ends
+	decay       int // damage lost after each tick
+	ignoreArmor bool
+	applied     int        // combatEngine.applied stamp, for cleanse/dispel ordering
+	source      *combatant // who applied it
+}
+
+// isDotKind reports whether code is one of the DoT types
+func isDotKind(code string) bool {
+	_, ok := dotIgnoresArmor[code]
+	return ok
+}
+
+// dotAppliedStat returns the CombatStats counter for damage applied by a DoT type
+func dotAppliedStat(s *CombatStats, kind string) *int {
+	switch kind {
+	case "poison":
+		return &s.PoisonApplied
+	case "burn":
+		return &s.BurnApplied
+	}
+	return &s.BleedApplied
+}
+
+// applyDotEffect adds a DoT stack sized from the victim's HP
+func applyDotEffect(e *combatEngine, ctx *effectContext) {
+	eff := ctx.effect
+	amount := resolveFactorValue(eff, ctx.target.maxHP, ctx.target.hp, ctx.damage)
+	if amount <= 0 {
+		// Too small to tick, but the proc still shows in the log
+		if e.logging {
+			e.emit(e.effectLogEntry(ctx, eff.CoreEffectCode, 0))
+		}
+		return
+	}
+	stack := dotStack{
+		kind:        eff.CoreEffectCode,
+		effectID:    eff.EffectID,
+		damage:      amount,
+		decay:       eff.Decay,
+		ignoreArmor: dotIgnoresArmor[eff.CoreEffectCode],
+		applied:     e.nextApplied(),
+		source:      ctx.source,
+	}
+	if eff.IgnoreArmor != nil {
+		stack.ignoreArmor = *eff.IgnoreArmor
+	}
+	if eff.Duration != nil && *eff.Duration > 0 {
+		stack.remaining = *eff.Duration
+	}
+	ctx.target.addDot(stack, eff.Stacking, eff.MaxStacks)
+	*dotAppliedStat(ctx.source.stats, stack.kind) += amount
+	if e.logging {
+		entry := e.effectLogEntry(ctx, stack.kind, amount)
+		if stack.remaining > 0 {
+			dur := stack.remaining
+			entry.Duration = &dur
+		}
+		e.emit(entry)
+	}
+	e.notifyBuff(ctx.target, stack.kind, stack.effectID, amount, buffApplied)
+}
+
+// addDot puts a stack on c according to the effect's stacking policy
+func (c *combatant) addDot(stack dotStack, stacking string, maxStacks int) {
+	switch stacking {
+	case stackingRefresh:
+		for i := range c.dots {
+			if c.dots[i].kind == stack.kind && c.dots[i].effectID == stack.effectID {
+				c.dots[i] = stack
+				return
+			}
+		}
+	case stackingMax:
+		if maxStacks <= 0 {
+			break
+		}
+		count, oldest := 0, -1
+		for i := range c.dots {
+			if c.dots[i].kind == stack.kind && c.dots[i].effectID == stack.effectID {
+				if oldest < 0 {
+					oldest = i
+				}
+				count++
+			}
+		}
+		if count >= maxStacks {
+			c.dots = append(c.dots[:oldest], c.dots[oldest+1:]...)
+		}
+	}
+	c.dots = append(c.dots, stack)
+}
+
+// hasDot reports whether c carries at least one stack of the DoT type
+func (c *combatant) hasDot(kind string) bool {
+	for _, d := range c.dots {
+		if d.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// tickDots deals c's DoT damage at the start of its turn, one entry per type,
+// then applies decay and drops the stacks that are used up
+func (e *combatEngine) tickDots(c *combatant) {
+	if len(c.dots) == 0 {
+		return
+	}
+	for _, kind := range dotKinds {
+		raw, armored, effectID := 0, 0, 0
+		var source *combatant // the oldest stack's applier and effect stand for the tick
+		for _, d := range c.dots {
+			if d.kind != kind {
+				continue
+			}
+			if source == nil {
+				source, effectID = d.source, d.effectID
+			}
+			if d.ignoreArmor {
+				raw += d.damage
+			} else {
+				armored += d.damage
+			}
+		}
+		if raw == 0 && armored == 0 {
+			continue
+		}
+		dmg := raw
+		if armored > 0 {
+			dmg += e.applyArmor(armored, c, 0)
+		}
+		absorbed := e.dealDamage(source, c, dmg, kind, effectID)
+		if e.logging {
+			e.emit(CombatLogEntry{Turn: e.turn, CharacterID: c.CharacterID, Action: kind, Factor: dmg, Absorbed: absorbed})
+		}
+	}
+
+	kept := c.dots[:0]
+	for _, d := range c.dots {
+		last := d.damage
+		d.damage -= d.decay
+		expired := d.damage <= 0
+		if d.remaining > 0 {
+			d.remaining--
+			expired = expired || d.remaining == 0
+		}
+		if !expired {
+			kept = append(kept, d)
+			continue
+		}
+		if e.logging {
+			eid := d.effectID
+			e.emit(CombatLogEntry{
+				Turn: e.turn, CharacterID: c.CharacterID, Action: "buff_expire",
+				Factor: last, EffectID: &eid, BuffType: d.kind,
+			})
+		}
+		e.notifyBuff(c, d.kind, d.effectID, last, buffExpired)
+	}
+	c.dots = kept
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+)
+
+// ── Effect handler registry ─────────────────────────────────────────────────
+//
+// Every core effect code is implemented once, as an effectHandler. The engine
+// resolves who the effect lands on, checks its condition and hands the
+// handler an effectContext — the handler never needs to know which trigger
+// fired it, so a new core effect works on every trigger as soon as it is
+// registered here.
+
+// combatTriggers are the trigger types the engine fires during a fight
+var combatTriggers = []string{
+	"on_start", "on_turn_start", "on_every_other_turn",
+	"on_hit", "on_crit", "on_hit_taken", "on_crit_taken", "on_turn_end",
+}
+
+// effectContext is what a handler receives when its effect fires
+type effectContext struct {
+	effect  *CombatTestEffect
+	trigger string
+	source  *combatant // owner of the effect
+	target  *combatant // who it lands on: the source for TargetSelf, the opponent otherwise
+	damage  int        // damage of the hit behind on_hit/on_crit/on_hit_taken/on_crit_taken, 0 otherwise
+}
+
+// effectHandler applies one core effect code
+type effectHandler struct {
+	apply        func(e *combatEngine, ctx *effectContext)
+	passive      bool // also valid with trigger "passive" (applied once before the fight)
+	ownPassive   bool // as a passive it always lands on its owner, whatever targetSelf says
+	selfPassive  bool // as a passive it only applies with targetSelf
+	usesFactor   bool // value goes through resolveFactorValue; otherwise factor type is ignored
+	usesDuration bool // a duration turns the effect into a temp buff; otherwise it is ignored
+	dot          bool // reads the DoT fields (stacking, maxStacks, decay, ignoreArmor)
+}
+
+// effectHandlers maps core effect codes to their handler
+var effectHandlers = map[string]*effectHandler{
+	"damage":  {apply: applyDamageEffect, usesFactor: true},
+	"heal":    {apply: applyHealEffect, usesFactor: true},
+	"bleed":   dotHandler,
+	"poison":  dotHandler,
+	"burn":    dotHandler,
+	"stun":    {apply: applyStunEffect, usesDuration: true},
+	"cleanse": {apply: applyCleanseEffect},
+	"dispel":  {apply: applyDispelEffect},
+	"shield":  {apply: applyShieldEffect, passive: true, ownPassive: true, usesFactor: true, usesDuration: true},
+
+	"dodge":              ownModifierHandler,
+	"crit":               ownModifierHandler,
+	"modify_damage":      selfModifierHandler,
+	"modify_dodge":       modifierHandler,
+	"modify_crit":        modifierHandler,
+	"modify_armor":       ownModifierHandler,
+	"modify_heal":        ownModifierHandler,
+	"modify_heal_done":   modifierHandler,
+	"anti_heal":          modifierHandler,
+	"counterattack":      ownModifierHandler,
+	"double_attack":      ownModifierHandler,
+	"consecutive_damage": ownModifierHandler,
+	"lifesteal":          ownModifierHandler,
+	"reflect_damage":     ownModifierHandler,
+	"armor_penetration":  ownModifierHandler,
+	"stun_resist":        ownModifierHandler,
+}
+
+// modifierHandler is for modifiers that can also be passive auras on the
+// enemy: modify_dodge, modify_crit, modify_heal_done and anti_heal
+var modifierHandler = &effectHandler{apply: applyModifierEffect, passive: true, usesDuration: true}
+
+// ownModifierHandler is for modifiers that as a passive always land on their
+// owner, as they have since before passive auras: its own dodge, crits,
+// attacks, armor and healing received
+var ownModifierHandler = &effectHandler{apply: applyModifierEffect, passive: true, ownPassive: true, usesDuration: true}
+
+// selfModifierHandler is for modify_damage, which as a passive has only ever
+// applied with targetSelf; rows without it do nothing and are reported so
+var selfModifierHandler = &effectHandler{apply: applyModifierEffect, passive: true, selfPassive: true, usesDuration: true}
+
+var dotHandler = &effectHandler{apply: applyDotEffect, usesFactor: true, usesDuration: true, dot: true}
+
+// damageTriggers fire with the damage of a hit in effectContext.damage
+var damageTriggers = map[string]bool{
+	"on_hit": true, "on_crit": true, "on_hit_taken": true, "on_crit_taken": true,
+}
+
+// supportsTrigger reports whether the handler does something for the trigger
+func (h *effectHandler) supportsTrigger(trigger string) bool {
+	if trigger == "passive" {
+		return h.passive
+	}
+	for _, t := range combatTriggers {
+		if t == trigger {
+			return true
+		}
+	}
+	return false
+}
+
+// modifierField returns the CombatModifiers field a modifier effect code adjusts (nil if none)
+func modifierField(mods *CombatModifiers, code string) *int {
+	switch code {
+	case "dodge", "modify_dodge":
+		return &mods.DodgeChance
+	case "crit", "modify_crit":
+		return &mods.CritChance
+	case "modify_damage":
+		return &mods.DamageModifier
+	case "modify_armor":
+		return &mods.ArmorModifier
+	case "modify_heal":
+		return &mods.HealModifier
+	case "modify_heal_done":
+		return &mods.HealDoneModifier
+	case "anti_heal":
+		return &mods.AntiHeal
+	case "counterattack":
+		return &mods.CounterChance
+	case "double_attack":
+		return &mods.DoubleAttackChance
+	case "consecutive_damage":
+		return &mods.ConsecutiveDamageBonus
+	case "lifesteal":
+		return &mods.Lifesteal
+	case "reflect_damage":
+		return &mods.ReflectDamage
+	case "armor_penetration":
+		return &mods.ArmorPenetration
+	case "stun_resist":
+		return &mods.StunResist
+	}
+	return nil
+}
+
+// ── Handlers ────────────────────────────────────────────────────────────────
+
+func applyDamageEffect(e *combatEngine, ctx *effectContext) {
+	val := resolveFactorValue(ctx.effect, ctx.source.maxHP, ctx.source.hp, ctx.damage)
+	absorbed := e.dealDamage(ctx.source, ctx.target, val, "damage", ctx.effect.EffectID)
+	if e.logging {
+		entry := e.effectLogEntry(ctx, "damage", val)
+		entry.Absorbed = absorbed
+		e.emit(entry)
+	}
+}
+
+func applyHealEffect(e *combatEngine, ctx *effectContext) {
+	val := resolveFactorValue(ctx.effect, ctx.source.maxHP, ctx.source.hp, ctx.damage)
+	healed, overheal := e.heal(ctx.source, ctx.target, val, "heal", ctx.effect.EffectID)
+	if e.logging {
+		entry := e.effectLogEntry(ctx, "heal", healed)
+		entry.Overheal = overheal
+		e.emit(entry)
+	}
+}
+
+// applyShieldEffect gives the target a layer of absorb points, sized from the
+// target's own max HP for percent_of_max_hp. A duration makes the layer expire
+// at the end of the target's turn; without one it lasts until broken.
+func applyShieldEffect(e *combatEngine, ctx *effectContext) {
+	eff := ctx.effect
+	points := resolveFactorValue(eff, ctx.target.maxHP, ctx.target.hp, ctx.damage)
+	if points <= 0 {
+		return
+	}
+	layer := shieldLayer{points: points, effectID: eff.EffectID}
+	if ctx.trigger != "passive" && eff.Duration != nil && *eff.Duration > 0 {
+		// +1 so the application turn doesn't consume a duration tick
+		layer.remaining = *eff.Duration + 1
+	}
+	ctx.target.shields = append(ctx.target.shields, layer)
+	if e.logging {
+		eid := eff.EffectID
+		entry := CombatLogEntry{Turn: e.turn, CharacterID: ctx.target.CharacterID, TargetID: ctx.target.CharacterID, Action: "shield", Factor: points, EffectID: &eid, TriggerType: ctx.trigger, BuffType: "shield"}
+		if ctx.source != ctx.target {
+			entry.SourceID = ctx.source.CharacterID
+		}
+		if layer.remaining > 0 {
+			dur := *eff.Duration
+			entry.Duration = &dur
+		}
+		e.emit(entry)
+	}
+	e.notifyBuff(ctx.target, "shield", eff.EffectID, points, buffApplied)
+}
+
+// applyModifierEffect adjusts one modifier on the target — permanently, or as
+// a temp buff when the effect has a duration (passives are always permanent)
+func applyModifierEffect(e *combatEngine, ctx *effectContext) {
+	eff := ctx.effect
+	field := modifierField(&ctx.target.mods, eff.CoreEffectCode)
+	if field == nil {
+		return
+	}
+	*field += eff.Value
+
+	if ctx.trigger == "passive" || eff.Duration == nil || *eff.Duration <= 0 {
+		return
+	}
+	// +1 so the application turn doesn't consume a duration tick
+	ctx.target.buffs = append(ctx.target.buffs, TempBuff{
+		ModifierType: eff.CoreEffectCode,
+		Value:        eff.Value,
+		Remaining:    *eff.Duration + 1,
+		EffectID:     eff.EffectID,
+		TriggerType:  ctx.trigger,
+		applied:      e.nextApplied(),
+	})
+	if e.logging {
+		// Buffs are logged on whoever wears them, with SourceID naming another
+		// caster, except on_start ones, which have always been logged on their caster
+		eid := eff.EffectID
+		dur := *eff.Duration
+		entry := CombatLogEntry{
+			Turn: e.turn, CharacterID: ctx.target.CharacterID, TargetID: ctx.target.CharacterID, Action: "buff", Factor: eff.Value,
+			EffectID: &eid, TriggerType: ctx.trigger, Duration: &dur, BuffType: eff.CoreEffectCode,
+		}
+		if ctx.trigger == "on_start" {
+			entry.CharacterID = ctx.source.CharacterID
+		} else if ctx.source != ctx.target {
+			entry.SourceID = ctx.source.CharacterID
+		}
+		e.emit(entry)
+	}
+	e.notifyBuff(ctx.target, eff.CoreEffectCode, eff.EffectID, eff.Value, buffApplied)
+}
+
+// effectLogEntry builds the log line for an effect proc, attributed to its
+// source
+func (e *combatEngine) effectLogEntry(ctx *effectContext, action string, factor int) CombatLogEntry {
+	eid := ctx.effect.EffectID
+	return CombatLogEntry{Turn: e.turn, CharacterID: ctx.source.CharacterID, TargetID: ctx.target.CharacterID, Action: action, Factor: factor, EffectID: &eid, TriggerType: ctx.trigger}
+}
+
+// ── Support report ──────────────────────────────────────────────────────────
+
+// EffectSupportEntry is one game.effects row checked against the engine
+type EffectSupportEntry struct {
+	EffectID       int      `json:"effectId"`
+	Name           string   `json:"name"`
+	CoreEffectCode string   `json:"coreEffectCode"`
+	TriggerType    string   `json:"triggerType"`
+	Status         string   `json:"status"` // supported, partial, unsupported
+	Notes          []string `json:"notes,omitempty"`
+}
+
+var knownFactorTypes = map[string]bool{
+	"percent": true, "flat": true, "percent_of_max_hp": true, "percent_of_missing_hp": true,
+	"percent_of_damage_dealt": true, "percent_of_damage_taken": true,
+}
+
+var knownConditionTypes = map[string]bool{
+	"hp_below_percent": true, "hp_above_percent": true,
+}
+
+// checkEffectSupport reports whether the engine does what an effect asks for.
+// "unsupported" means the effect never does anything; "partial" means some of
+// its fields are ignored.
+func checkEffectSupport(eff CombatTestEffect) (string, []string) {
+	if eff.CoreEffectCode == "" {
+		return "unsupported", []string{"no core effect code"}
+	}
+	h := effectHandlers[eff.CoreEffectCode]
+	if h == nil {
+		return "unsupported", []string{"engine has no handler for " + eff.CoreEffectCode}
+	}
+	if !h.supportsTrigger(eff.TriggerType) {
+		return "unsupported", []string{eff.CoreEffectCode + " does nothing on trigger " + eff.TriggerType}
+	}
+	if h.selfPassive && eff.TriggerType == "passive" && !eff.TargetSelf {
+		return "unsupported", []string{"passive " + eff.CoreEffectCode + " does nothing without targetSelf"}
+	}
+
+	var notes []string
+	if h.usesFactor {
+		if !knownFactorTypes[eff.FactorType] {
+			notes = append(notes, "unknown factor type "+eff.FactorType+" is treated as percent")
+		} else if (eff.FactorType == "percent_of_damage_dealt" || eff.FactorType == "percent_of_damage_taken") && !damageTriggers[eff.TriggerType] {
+			notes = append(notes, eff.FactorType+" has no hit to read on "+eff.TriggerType+" and always resolves to 0")
+		}
+	} else if eff.FactorType != "" && eff.FactorType != "percent" && eff.FactorType != "flat" {
+		notes = append(notes, "factor type "+eff.FactorType+" is ignored by "+eff.CoreEffectCode+" (value is used as-is)")
+	}
+	if eff.Duration != nil && *eff.Duration > 0 {
+		if !h.usesDuration {
+			notes = append(notes, "duration is ignored by "+eff.CoreEffectCode)
+		} else if eff.TriggerType == "passive" {
+			notes = append(notes, "duration is ignored for passive effects")
+		}
+	}
+	if h.dot {
+		if !knownStackingPolicies[eff.Stacking] {
+			notes = append(notes, "unknown stacking policy "+eff.Stacking+" is treated as stack")
+		} else if eff.Stacking == stackingMax && eff.MaxStacks <= 0 {
+			notes = append(notes, "max_stacks without maxStacks stacks without a cap")
+		}
+	} else if eff.Stacking != "" || eff.MaxStacks != 0 || eff.Decay != 0 || eff.IgnoreArmor != nil {
+		notes = append(notes, "DoT fields are ignored by "+eff.CoreEffectCode)
+	}
+	if eff.CoreEffectCode == "cleanse" || eff.CoreEffectCode == "dispel" {
+		if !knownRemoveOrders[eff.RemoveOrder] {
+			notes = append(notes, "unknown remove order "+eff.RemoveOrder+" is treated as newest")
+		}
+		if eff.CoreEffectCode == "cleanse" && !eff.TargetSelf {
+			notes = append(notes, "cleanse without targetSelf removes the opponent's debuffs")
+		} else if eff.CoreEffectCode == "dispel" && eff.TargetSelf {
+			notes = append(notes, "dispel with targetSelf removes the owner's own buffs")
+		}
+	} else if eff.RemoveOrder != "" {
+		notes = append(notes, "removeOrder is ignored by "+eff.CoreEffectCode)
+	}
+	if eff.CoreEffectCode == "anti_heal" && eff.TargetSelf {
+		notes = append(notes, "anti_heal with targetSelf blocks the owner's own healing")
+	}
+	if eff.TriggerType == "passive" {
+		if eff.Cooldown != 0 || eff.MaxProcs != 0 || eff.ProcChance != nil {
+			notes = append(notes, "cooldown, maxProcs and procChance are ignored for passive effects")
+		}
+		if eff.Consumable {
+			notes = append(notes, "consumable is ignored for passive effects")
+		}
+	} else if eff.ProcChance != nil && *eff.ProcChance <= 0 {
+		notes = append(notes, fmt.Sprintf("procChance %d%% never procs", *eff.ProcChance))
+	}
+	hasLegacy := eff.ConditionType != nil && *eff.ConditionType != ""
+	if hasLegacy && !knownConditionTypes[*eff.ConditionType] {
+		notes = append(notes, "unknown condition type "+*eff.ConditionType+" always passes")
+	}
+	if eff.Condition != nil {
+		for _, p := range eff.Condition.problems() {
+			notes = append(notes, "condition: "+p)
+		}
+	}
+	if (hasLegacy || eff.Condition != nil) && eff.TriggerType == "passive" {
+		notes = append(notes, "conditions are not evaluated for passive effects")
+	}
+	if len(notes) > 0 {
+		return "partial", notes
+	}
+	return "supported", nil
+}
+
+// supportMatrix lists the triggers each registered core effect code handles
+func supportMatrix() map[string][]string {
+	matrix := map[string][]string{}
+	for code, h := range effectHandlers {
+		triggers := []string{}
+		if h.passive {
+			triggers = append(triggers, "passive")
+		}
+		triggers = append(triggers, combatTriggers...)
+		matrix[code] = triggers
+	}
+	return matrix
+}
+
+// handleGetEffectSupportReport cross-checks every game.effects row against the engine
+func handleGetEffectSupportReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	effects, err := getAllEffects()
+	if err != nil {
+		log.Printf("ERROR: Failed to get effects: %v", err)
+		http.Error(w, "Failed to load effects", http.StatusInternalServerError)
+		return
+	}
+
+	entries := []EffectSupportEntry{}
+	counts := map[string]int{"supported": 0, "partial": 0, "unsupported": 0}
+	for _, e := range effects {
+		eff := effectFromTemplate(e.ID, e, e.Factor)
+		status, notes := checkEffectSupport(eff)
+		counts[status]++
+		entries = append(entries, EffectSupportEntry{
+			EffectID:       e.ID,
+			Name:           e.Name,
+			CoreEffectCode: eff.CoreEffectCode,
+			TriggerType:    eff.TriggerType,
+			Status:         status,
+			Notes:          notes,
+		})
+	}
+	// Worst first so the problems are at the top
+	rank := map[string]int{"unsupported": 0, "partial": 1, "supported": 2}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return rank[entries[i].Status] < rank[entries[j].Status]
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"effects": entries,
+		"counts":  counts,
+		"matrix":  supportMatrix(),
+	})
+}
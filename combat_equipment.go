@@ -0,0 +1,140 @@
+package main
+
+import "fmt"
+
+// ── Equipment ───────────────────────────────────────────────────────────────
+//
+// A loadout is the game.items a character wears: at most one per slot, the
+// slot being the item's game.item_type. An item with a socket can hold one
+// gem. Every worn item and gem adds its stats and weapon damage to the
+// character and brings its effect (effect_id at effect_factor, or at the
+// effect's own factor when the item doesn't set one) as a passive or
+// triggered effect like any talent's.
+
+// equipmentSlots are the item types a character wears, one item each;
+// gems only go into sockets and the other types are never worn
+var equipmentSlots = map[string]bool{
+	"head": true, "chest": true, "hands": true, "feet": true, "belt": true,
+	"legs": true, "back": true, "amulet": true, "weapon": true,
+}
+
+// EquippedItem is one worn item and the gem in its socket
+type EquippedItem struct {
+	ItemID int  `json:"itemId"`
+	GemID  *int `json:"gemId,omitempty"` // a gem item; the item needs a socket
+}
+
+// validateLoadout checks that every item exists and is wearable, that no
+// slot is used twice and that gems sit in sockets
+func validateLoadout(loadout []EquippedItem, items map[int]Item) error {
+	used := map[string]int{}
+	for _, eq := range loadout {
+		item, ok := items[eq.ItemID]
+		if !ok {
+			return fmt.Errorf("item %d not found", eq.ItemID)
+		}
+		if !equipmentSlots[item.Type] {
+			return fmt.Errorf("item %d (%s) is a %s, which can't be worn", item.ID, item.Name, item.Type)
+		}
+		if other, taken := used[item.Type]; taken {
+			return fmt.Errorf("items %d and %d both go in the %s slot", other, item.ID, item.Type)
+		}
+		used[item.Type] = item.ID
+		if eq.GemID == nil {
+			continue
+		}
+		if !item.Socket {
+			return fmt.Errorf("item %d (%s) has no socket", item.ID, item.Name)
+		}
+		gem, ok := items[*eq.GemID]
+		if !ok {
+			return fmt.Errorf("gem %d not found", *eq.GemID)
+		}
+		if gem.Type != "gem" {
+			return fmt.Errorf("item %d (%s) is a %s, not a gem", gem.ID, gem.Name, gem.Type)
+		}
+	}
+	return nil
+}
+
+// equipCharacter puts a loadout on c. Items that don't exist or can't be
+// worn are skipped (validateLoadout reports them); effects are numbered on
+// from c's highest effect ID.
+func equipCharacter(c *CombatCharacter, loadout []EquippedItem, items map[int]Item, effects map[int]Effect) {
+	nextID := ownEffects(c)
+
+	wear := func(item Item) {
+		c.Strength += derefInt(item.Strength)
+		c.Stamina += derefInt(item.Stamina)
+		c.Agility += derefInt(item.Agility)
+		c.Luck += derefInt(item.Luck)
+		c.Armor += derefInt(item.Armor)
+		c.MinDamage += derefInt(item.MinDamage)
+		c.MaxDamage += derefInt(item.MaxDamage)
+		if item.EffectID == nil {
+			return
+		}
+		e, ok := effects[*item.EffectID]
+		if !ok {
+			return
+		}
+		value := e.Factor
+		if item.EffectFactor != nil {
+			value = *item.EffectFactor
+		}
+		c.Effects = append(c.Effects, effectFromTemplate(nextID, e, value))
+		nextID++
+	}
+
+	used := map[string]bool{}
+	for _, eq := range loadout {
+		item, ok := items[eq.ItemID]
+		if !ok || !equipmentSlots[item.Type] || used[item.Type] {
+			continue
+		}
+		used[item.Type] = true
+		wear(item)
+		if eq.GemID == nil || !item.Socket {
+			continue
+		}
+		if gem, ok := items[*eq.GemID]; ok && gem.Type == "gem" {
+			wear(gem)
+		}
+	}
+	if c.Stamina < 1 {
+		c.Stamina = 1
+	}
+}
+
+// ownEffects copies c's effects, so that appending to them leaves characters
+// sharing the slice alone, and returns the next free effect ID
+func ownEffects(c *CombatCharacter) int {
+	nextID := 1
+	for _, eff := range c.Effects {
+		if eff.EffectID >= nextID {
+			nextID = eff.EffectID + 1
+		}
+	}
+	c.Effects = append([]CombatTestEffect(nil), c.Effects...)
+	return nextID
+}
+
+// loadItemLookup fetches game.items indexed by ID
+func loadItemLookup() (map[int]Item, error) {
+	items, err := getAllItems()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int]Item, len(items))
+	for _, item := range items {
+		out[item.ID] = item
+	}
+	return out, nil
+}
+
+func derefInt(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
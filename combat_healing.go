@@ -0,0 +1,49 @@
+package main
+
+// ── Healing ─────────────────────────────────────────────────────────────────
+//
+// Every heal goes through heal(): the base amount is scaled by the healer's
+// modify_heal_done, then by the target's modify_heal, then cut by the
+// target's anti_heal. What would lift the target above max HP is overheal:
+// it is logged and counted but never applied. A negative amount drains HP
+// as it always has, dealt as damage so it is counted and can kill.
+
+// heal restores HP on target, credited to source; cause and effectID tell
+// observers where it came from. It returns the amount after modifiers and
+// the part of it lost to overheal.
+func (e *combatEngine) heal(source *combatant, target *combatant, amount int, cause string, effectID int) (int, int) {
+	if amount < 0 {
+		e.dealDamage(source, target, -amount, cause, effectID)
+		return amount, 0
+	}
+	if amount == 0 {
+		return 0, 0
+	}
+
+	amount = amount * (100 + source.mods.HealDoneModifier) / 100
+	amount = amount * (100 + target.mods.HealModifier) / 100
+	if amount < 0 {
+		amount = 0
+	}
+	if anti := target.mods.AntiHeal; anti > 0 {
+		if anti > 100 {
+			anti = 100
+		}
+		blocked := amount * anti / 100
+		target.stats.HealingBlocked += blocked
+		amount -= blocked
+	}
+
+	overheal := 0
+	if room := target.maxHP - target.hp; amount > room {
+		overheal = amount - room
+		if overheal > amount {
+			overheal = amount
+		}
+	}
+	target.hp += amount - overheal
+	source.stats.HealingDone += amount - overheal
+	source.stats.Overheal += overheal
+	e.notifyHeal(source, target, amount-overheal, overheal, cause, effectID)
+	return amount, overheal
+}
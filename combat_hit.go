@@ -0,0 +1,45 @@
+package main
+
+// ── Hit riders ──────────────────────────────────────────────────────────────
+//
+// lifesteal, reflect_damage and armor_penetration are modifiers (passive, or
+// temp buffs when triggered with a duration) that ride on every attack and
+// counterattack: penetration while the hit is mitigated, lifesteal and
+// reflect right after it lands. Reflected damage is not an attack, so it never
+// procs triggers, lifesteal or another reflect.
+
+// mitigateHit applies armor to an attack, logging what armor penetration added
+func (e *combatEngine) mitigateHit(damage int, attacker *combatant, defender *combatant) int {
+	pen := attacker.mods.ArmorPenetration
+	mitigated := e.applyArmor(damage, defender, pen)
+	if pen > 0 {
+		if gained := mitigated - e.applyArmor(damage, defender, 0); gained > 0 {
+			if e.logging {
+				e.emit(CombatLogEntry{Turn: e.turn, CharacterID: attacker.CharacterID, TargetID: defender.CharacterID, Action: "armor_penetration", Factor: gained})
+			}
+		}
+	}
+	return mitigated
+}
+
+// afterHit heals the attacker for lifesteal and sends reflect back at it
+func (e *combatEngine) afterHit(attacker *combatant, defender *combatant, damage int, absorbed int) {
+	if pct := attacker.mods.Lifesteal; pct > 0 {
+		if amount := (damage - absorbed) * pct / 100; amount > 0 {
+			healed, overheal := e.heal(attacker, attacker, amount, "lifesteal", 0)
+			attacker.stats.LifestealHealing += healed - overheal
+			if e.logging {
+				e.emit(CombatLogEntry{Turn: e.turn, CharacterID: attacker.CharacterID, TargetID: attacker.CharacterID, Action: "lifesteal", Factor: healed, Overheal: overheal})
+			}
+		}
+	}
+	if pct := defender.mods.ReflectDamage; pct > 0 {
+		if amount := damage * pct / 100; amount > 0 {
+			reflectAbsorbed := e.dealDamage(defender, attacker, amount, "reflect_damage", 0)
+			defender.stats.DamageReflected += amount - reflectAbsorbed
+			if e.logging {
+				e.emit(CombatLogEntry{Turn: e.turn, CharacterID: defender.CharacterID, TargetID: attacker.CharacterID, Action: "reflect_damage", Factor: amount, Absorbed: reflectAbsorbed})
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+// ── Initiative ──────────────────────────────────────────────────────────────
+//
+// Who acts when is a rules-profile choice:
+//
+//   fixed   — fighters act in the order passed, teams interleaved, so the
+//             first combatant always acts first (callers such as runMatch
+//             alternate sides to stay fair)
+//   agility — higher agility acts first every turn; luck breaks ties, then a coin flip
+//   speed   — action timeline: the slowest fighter acts once per turn and a
+//             faster one acts every slowest/own agility of a turn, so twice
+//             the agility means two actions per turn
+//
+// In agility and speed modes every action is preceded by an "initiative" log
+// entry whose factor is the actor's position in that turn (1 = first).
+
+const (
+	initiativeFixed   = "fixed"
+	initiativeAgility = "agility"
+	initiativeSpeed   = "speed"
+)
+
+// timelineTurn is the length of one turn on the speed timeline
+const timelineTurn = 1000
+
+// compareInitiative returns 1 if a acts before b, -1 if b acts first, 0 on a full tie
+func compareInitiative(a *combatant, b *combatant) int {
+	switch {
+	case a.Agility != b.Agility:
+		if a.Agility > b.Agility {
+			return 1
+		}
+		return -1
+	case a.Luck != b.Luck:
+		if a.Luck > b.Luck {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+// winnerAfter checks for a wiped team after actor's turn; the opposing team
+// is checked first. Returns the winning team (1 or 2) or 0.
+func (e *combatEngine) winnerAfter(actor *combatant) int {
+	if !e.teamAlive(1 - actor.team) {
+		return actor.team + 1
+	}
+	if !e.teamAlive(actor.team) {
+		return 2 - actor.team
+	}
+	return 0
+}
+
+func (e *combatEngine) logInitiative(actor *combatant, position int) {
+	if e.logging {
+		e.emit(CombatLogEntry{Turn: e.turn, CharacterID: actor.CharacterID, Action: "initiative", Factor: position})
+	}
+}
+
+// initiativeOrder sorts fighters by compareInitiative, flipping a coin for
+// every full tie it meets
+func (e *combatEngine) initiativeOrder(fighters []*combatant) []*combatant {
+	order := append([]*combatant(nil), fighters...)
+	for i := 1; i < len(order); i++ {
+		for j := i; j > 0; j-- {
+			c := compareInitiative(order[j], order[j-1])
+			if c == 0 && e.rng.Intn(2) == 1 {
+				c = 1
+			}
+			if c <= 0 {
+				break
+			}
+			order[j], order[j-1] = order[j-1], order[j]
+		}
+	}
+	return order
+}
+
+// runTurns plays turns in an order fixed at the start (fixed/agility modes);
+// dead fighters are skipped. Returns the winning team, or 0 if the turn
+// limit was reached.
+func (e *combatEngine) runTurns() int {
+	order := e.fighters
+	logOrder := e.rules.Initiative == initiativeAgility
+	if logOrder {
+		order = e.initiativeOrder(order)
+	}
+
+	for e.turn = 1; e.turn <= e.rules.MaxTurns; e.turn++ {
+		position := 0
+		for _, actor := range order {
+			if actor.hp <= 0 {
+				continue
+			}
+			defender := e.pickTarget(actor)
+			position++
+			if logOrder {
+				e.logInitiative(actor, position)
+			}
+			e.executeTurn(actor, defender)
+			if w := e.winnerAfter(actor); w != 0 {
+				return w
+			}
+		}
+	}
+	return 0
+}
+
+// runTimeline plays the speed-based action timeline; dead fighters drop off.
+// Returns the winning team, or 0 if the turn limit was reached.
+func (e *combatEngine) runTimeline() int {
+	fighters := e.fighters
+	speed := make([]int, len(fighters))
+	slowest := 0
+	for i, f := range fighters {
+		speed[i] = f.Agility
+		if speed[i] < 1 {
+			speed[i] = 1
+		}
+		if i == 0 || speed[i] < slowest {
+			slowest = speed[i]
+		}
+	}
+
+	interval := make([]int, len(fighters))
+	next := make([]int, len(fighters))
+	for i := range fighters {
+		interval[i] = timelineTurn * slowest / speed[i]
+		if interval[i] < 1 {
+			interval[i] = 1
+		}
+		next[i] = interval[i]
+	}
+
+	position := 0
+	for {
+		i := -1
+		for j, f := range fighters {
+			if f.hp <= 0 {
+				continue
+			}
+			if i < 0 || next[j] < next[i] || (next[j] == next[i] && compareInitiative(f, fighters[i]) > 0) {
+				i = j
+			}
+		}
+		turn := (next[i] + timelineTurn - 1) / timelineTurn
+		if turn > e.rules.MaxTurns {
+			return 0
+		}
+		if turn != e.turn {
+			e.turn = turn
+			position = 0
+		}
+		position++
+
+		actor := fighters[i]
+		e.logInitiative(actor, position)
+		e.executeTurn(actor, e.pickTarget(actor))
+		next[i] += interval[i]
+		if w := e.winnerAfter(actor); w != 0 {
+			return w
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ── Narration ───────────────────────────────────────────────────────────────
+//
+// narrateCombat turns a fight's log into readable lines for QA bug reports
+// and the quest generator's fight summaries:
+//
+//	Turn 3: Rookbridge Brute crits for 42, bleeding 6 (Serrated Edge)
+//
+// Consecutive entries of the same actor in the same turn share a line; the
+// initiative entries are left out. Log effect IDs are numbered per fighter,
+// so an effect is named through its owner's effect list: by the game.effects
+// row it was built from when that has a name, by ID otherwise. The last line
+// says who won.
+
+// narrateCombat returns the narration of a 1v1 fight between the given
+// characters; effectNames (game.effects ID to name) may be nil
+func narrateCombat(result *CombatResult, chars []*CombatCharacter, effectNames map[int]string) []string {
+	n := &combatNarrator{
+		fighters: map[int]string{
+			result.Header.Combatant1.ID: result.Header.Combatant1.Name,
+			result.Header.Combatant2.ID: result.Header.Combatant2.Name,
+		},
+		effects: map[int]map[int]string{},
+	}
+	for _, c := range chars {
+		names := map[int]string{}
+		for _, eff := range c.Effects {
+			if name := effectNames[eff.SourceEffectID]; eff.SourceEffectID != 0 && name != "" {
+				names[eff.EffectID] = name
+			}
+		}
+		n.effects[c.CharacterID] = names
+	}
+
+	lines := []string{}
+	var phrases []string
+	turn, actor := -1, 0
+	flush := func() {
+		if len(phrases) == 0 {
+			return
+		}
+		prefix := fmt.Sprintf("Turn %d", turn)
+		if turn == 0 {
+			prefix = "Start"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s %s", prefix, n.name(actor), strings.Join(phrases, ", ")))
+		phrases = nil
+	}
+	for _, entry := range result.Log {
+		if entry.Action == "initiative" {
+			continue
+		}
+		if entry.Turn != turn || entry.CharacterID != actor {
+			flush()
+			turn, actor = entry.Turn, entry.CharacterID
+		}
+		phrases = append(phrases, n.phrase(entry))
+	}
+	flush()
+	return append(lines, n.outcome(result))
+}
+
+// narrateTestCombat adds the narration to a tester result when the request
+// asked for it. Missing effect names aren't worth failing the fight over.
+func narrateTestCombat(req *CombatTestRequest, result *CombatResult, c1 *CombatCharacter, c2 *CombatCharacter) {
+	if !req.Narrate {
+		return
+	}
+	names, err := loadEffectNames()
+	if err != nil {
+		log.Printf("Error loading effect names for narration: %v", err)
+	}
+	result.Narration = narrateCombat(result, []*CombatCharacter{c1, c2}, names)
+}
+
+// loadEffectNames maps every game.effects ID to its name
+func loadEffectNames() (map[int]string, error) {
+	effects, err := getAllEffects()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int]string, len(effects))
+	for _, eff := range effects {
+		names[eff.ID] = eff.Name
+	}
+	return names, nil
+}
+
+type combatNarrator struct {
+	fighters map[int]string
+	effects  map[int]map[int]string // fighter ID -> log effect ID -> name
+}
+
+func (n *combatNarrator) name(id int) string {
+	if name, ok := n.fighters[id]; ok && name != "" {
+		return name
+	}
+	return fmt.Sprintf("Fighter #%d", id)
+}
+
+// effect names the effect behind an entry, "" for entries without one
+func (n *combatNarrator) effect(entry CombatLogEntry) string {
+	if entry.EffectID == nil {
+		return ""
+	}
+	owner := entry.CharacterID
+	if entry.SourceID != 0 {
+		owner = entry.SourceID
+	}
+	if name := n.effects[owner][*entry.EffectID]; name != "" {
+		return name
+	}
+	return fmt.Sprintf("effect #%d", *entry.EffectID)
+}
+
+// phrase describes one log entry without its actor
+func (n *combatNarrator) phrase(entry CombatLogEntry) string {
+	target := n.name(entry.TargetID)
+	self := entry.TargetID == 0 || entry.TargetID == entry.CharacterID
+	var p string
+	switch entry.Action {
+	case "attack":
+		p = fmt.Sprintf("hits for %d", entry.Factor)
+	case "crit":
+		p = fmt.Sprintf("crits for %d", entry.Factor)
+	case "counterattack":
+		p = fmt.Sprintf("counters for %d", entry.Factor)
+	case "dodge":
+		p = fmt.Sprintf("misses as %s dodges", target)
+	case "damage":
+		p = fmt.Sprintf("deals %d damage", entry.Factor)
+	case "reflect_damage":
+		p = fmt.Sprintf("reflects %d damage", entry.Factor)
+	case "armor_penetration":
+		p = fmt.Sprintf("pierces armor for %d extra", entry.Factor)
+	case "bleed", "poison", "burn":
+		p = n.dotPhrase(entry)
+	case "heal":
+		p = fmt.Sprintf("heals for %d", entry.Factor)
+		if !self {
+			p = fmt.Sprintf("heals %s for %d", target, entry.Factor)
+		}
+		if entry.Overheal > 0 {
+			p += fmt.Sprintf(" (%d overheal)", entry.Overheal)
+		}
+	case "lifesteal":
+		p = fmt.Sprintf("drains %d HP", entry.Factor)
+	case "stun":
+		p = "stuns " + target
+		if entry.Duration != nil && *entry.Duration > 1 {
+			p += fmt.Sprintf(" for %d turns", *entry.Duration)
+		}
+	case "stunned":
+		p = "is stunned and loses the turn"
+	case "stun_resisted":
+		p = target + " resists the stun"
+	case "stun_immune":
+		p = target + " is immune to stuns"
+	case "stun_immunity":
+		p = "shakes off the stun"
+		if entry.Duration != nil {
+			p += fmt.Sprintf(" and is immune for %d turns", *entry.Duration)
+		}
+	case "shield":
+		p = fmt.Sprintf("gains a %d-point shield", entry.Factor)
+		if entry.SourceID != 0 {
+			p += " from " + n.name(entry.SourceID)
+		}
+	case "shield_break":
+		p = "loses a shield as it breaks"
+	case "buff":
+		p = "gains " + buffLabel(entry.BuffType, entry.Factor)
+		if !self {
+			p = "gives " + target + " " + buffLabel(entry.BuffType, entry.Factor)
+		}
+		if entry.SourceID != 0 {
+			p += " from " + n.name(entry.SourceID)
+		}
+		if entry.Duration != nil {
+			p += fmt.Sprintf(" for %d turns", *entry.Duration)
+		}
+	case "buff_expire":
+		p = buffLabel(entry.BuffType, entry.Factor) + " wears off"
+	case "buff_removed":
+		p = "loses " + buffLabel(entry.BuffType, entry.Factor)
+	case "cleanse":
+		p = fmt.Sprintf("cleanses %d debuff(s)", entry.Factor)
+		if !self {
+			p = fmt.Sprintf("cleanses %d debuff(s) from %s", entry.Factor, target)
+		}
+	case "dispel":
+		p = fmt.Sprintf("dispels %d buff(s) from %s", entry.Factor, target)
+	case "potion":
+		p = "drinks a potion"
+	default:
+		p = fmt.Sprintf("%s (%d)", entry.Action, entry.Factor)
+	}
+	if entry.Absorbed > 0 {
+		p += fmt.Sprintf(" (%d absorbed)", entry.Absorbed)
+	}
+	// Expiries and removals name what went, not the effect behind it
+	if name := n.effect(entry); name != "" && entry.Action != "buff_expire" && entry.Action != "buff_removed" && entry.Action != "shield_break" {
+		p += " (" + name + ")"
+	}
+	return p
+}
+
+// dotPhrase is a DoT application (carries its effect) or tick (doesn't)
+func (n *combatNarrator) dotPhrase(entry CombatLogEntry) string {
+	if entry.EffectID != nil {
+		if entry.Factor <= 0 {
+			return "fails to apply " + entry.Action
+		}
+		verb := map[string]string{"bleed": "bleeding", "poison": "poisoning", "burn": "burning"}[entry.Action]
+		return fmt.Sprintf("%s %d", verb, entry.Factor)
+	}
+	switch entry.Action {
+	case "poison":
+		return fmt.Sprintf("takes %d poison damage", entry.Factor)
+	case "burn":
+		return fmt.Sprintf("burns for %d", entry.Factor)
+	}
+	return fmt.Sprintf("bleeds for %d", entry.Factor)
+}
+
+// buffLabel reads a modifier, shield or DoT with its value: "armor +20"
+func buffLabel(buffType string, value int) string {
+	switch {
+	case buffType == "shield":
+		return "a shield"
+	case buffType == "stun_immunity":
+		return "stun immunity"
+	case isDotKind(buffType):
+		return fmt.Sprintf("%s (%d)", buffType, value)
+	}
+	label := strings.ReplaceAll(strings.TrimPrefix(buffType, "modify_"), "_", " ")
+	return fmt.Sprintf("%s %+d", label, value)
+}
+
+// outcome is the closing line: who won, how, and with how much HP
+func (n *combatNarrator) outcome(result *CombatResult) string {
+	winner := result.SummaryFor(result.Header.WinnerID)
+	if winner == nil {
+		return fmt.Sprintf("The fight ends after %d turns", result.Header.Turns)
+	}
+	how := "wins"
+	if result.Header.TimedOut {
+		how = "wins on HP at the turn limit"
+	}
+	return fmt.Sprintf("%s %s after %d turns with %d/%d HP left", n.name(winner.ID), how, result.Header.Turns, winner.HPEnd, winner.MaxHP)
+}
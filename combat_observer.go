@@ -0,0 +1,319 @@
+package main
+
+// ── Observers ───────────────────────────────────────────────────────────────
+//
+// The engine reports every event of a fight to its observers as it happens.
+// The combat log is one of them (combatLog); analyses that only need a few
+// numbers, like the bulk and build tournaments, plug in their own observer
+// through CombatOptions.Observers instead of re-reading the log. Observers
+// get character IDs rather than live fighters so they can't change the fight.
+
+// CombatObserver receives the events of a fight in order
+type CombatObserver interface {
+	TurnStart(turn int, actorID int)
+	Attack(ev AttackEvent)
+	DamageApplied(ev DamageEvent)
+	Heal(ev HealEvent)
+	EffectProc(ev EffectProcEvent)
+	BuffChange(ev BuffChangeEvent)
+	Death(ev DeathEvent)
+	Snapshot(point TimelinePoint)
+	LogEntry(entry CombatLogEntry)
+}
+
+// ObserverNeeds is optionally implemented by observers that skip the log or
+// the timeline. Log entries and timeline points cost the engine allocations,
+// so it only builds them when some observer reads them; observers without
+// ObserverNeeds are taken to read both.
+type ObserverNeeds interface {
+	ReadsLog() bool
+	ReadsSnapshots() bool
+}
+
+// NopObserver ignores every event; embed it to implement only some of them
+type NopObserver struct{}
+
+func (NopObserver) TurnStart(int, int)            {}
+func (NopObserver) Attack(AttackEvent)            {}
+func (NopObserver) DamageApplied(DamageEvent)     {}
+func (NopObserver) Heal(HealEvent)                {}
+func (NopObserver) EffectProc(EffectProcEvent)    {}
+func (NopObserver) BuffChange(BuffChangeEvent)    {}
+func (NopObserver) Death(DeathEvent)              {}
+func (NopObserver) Snapshot(TimelinePoint)        {}
+func (NopObserver) LogEntry(entry CombatLogEntry) {}
+
+// AttackEvent is one attack or counterattack after its dodge and crit rolls
+type AttackEvent struct {
+	Turn       int
+	AttackerID int
+	DefenderID int
+	Dodged     bool
+	Crit       bool
+	Counter    bool
+	Double     bool // the bonus attack of a double_attack
+	Damage     int  // after armor, before shields; 0 when dodged
+}
+
+// DamageEvent is damage landing on a fighter, shields included
+type DamageEvent struct {
+	Turn     int
+	SourceID int // who dealt it (the DoT's applier for ticks), 0 if unknown
+	TargetID int
+	EffectID int    // the damage effect or DoT stack behind it, 0 for attacks and reflect
+	Cause    string // attack, crit, counterattack, damage, reflect_damage, bleed, poison, burn
+	Amount   int    // HP lost
+	Absorbed int    // soaked up by shields
+	HPLeft   int
+}
+
+// HealEvent is HP restored to a fighter after heal modifiers and anti-heal
+type HealEvent struct {
+	Turn     int
+	SourceID int
+	TargetID int
+	EffectID int    // the heal effect, 0 for lifesteal
+	Cause    string // heal, lifesteal
+	Amount   int    // HP restored
+	Overheal int    // healing above max HP, not applied
+}
+
+// EffectProcEvent is a triggered effect passing its condition and proc limits
+type EffectProcEvent struct {
+	Turn      int
+	OwnerID   int
+	TargetIDs []int // several for AoE effects; reused after the call, copy it to keep it
+	EffectID  int
+	Code      string
+	Trigger   string
+}
+
+// Buff changes
+const (
+	buffApplied = "applied"
+	buffExpired = "expired"
+	buffRemoved = "removed" // cleansed, dispelled or (shields) broken
+)
+
+// BuffChangeEvent is a temp buff, shield or DoT stack coming or going
+type BuffChangeEvent struct {
+	Turn        int
+	CharacterID int    // the wearer
+	BuffType    string // modifier code, shield, bleed, poison or burn
+	EffectID    int
+	Value       int
+	Change      string // applied, expired, removed
+}
+
+// DeathEvent is a fighter dropping to 0 HP
+type DeathEvent struct {
+	Turn        int
+	CharacterID int
+	KillerID    int // 0 if unknown
+	Cause       string
+}
+
+// combatLog is the observer that records the CombatLogEntry list of a fight
+type combatLog struct {
+	NopObserver
+	entries []CombatLogEntry
+}
+
+func (l *combatLog) LogEntry(entry CombatLogEntry) {
+	l.entries = append(l.entries, entry)
+}
+
+func (l *combatLog) ReadsLog() bool       { return true }
+func (l *combatLog) ReadsSnapshots() bool { return false }
+
+// The tournaments' metrics need neither the log nor the timeline
+func (o *metricsObserver) ReadsLog() bool       { return false }
+func (o *metricsObserver) ReadsSnapshots() bool { return false }
+
+// watch hands the fight to observers and works out once whether any of them
+// reads log entries or timeline points; the engine builds neither otherwise
+func (e *combatEngine) watch(observers ...CombatObserver) {
+	e.observers = observers
+	e.logging, e.charting = false, false
+	for _, o := range observers {
+		n, ok := o.(ObserverNeeds)
+		e.logging = e.logging || !ok || n.ReadsLog()
+		e.charting = e.charting || !ok || n.ReadsSnapshots()
+	}
+}
+
+// emit hands a log entry to every observer
+func (e *combatEngine) emit(entry CombatLogEntry) {
+	for _, o := range e.observers {
+		o.LogEntry(entry)
+	}
+}
+
+func (e *combatEngine) notifyTurnStart(c *combatant) {
+	for _, o := range e.observers {
+		o.TurnStart(e.turn, c.CharacterID)
+	}
+}
+
+func (e *combatEngine) notifyAttack(ev AttackEvent) {
+	ev.Turn = e.turn
+	for _, o := range e.observers {
+		o.Attack(ev)
+	}
+}
+
+func (e *combatEngine) notifyBuff(c *combatant, buffType string, effectID int, value int, change string) {
+	ev := BuffChangeEvent{Turn: e.turn, CharacterID: c.CharacterID, BuffType: buffType, EffectID: effectID, Value: value, Change: change}
+	for _, o := range e.observers {
+		o.BuffChange(ev)
+	}
+}
+
+func (e *combatEngine) notifyProc(owner *combatant, eff *CombatTestEffect, trigger string, targets ...*combatant) {
+	if len(e.observers) == 0 {
+		return
+	}
+	// One buffer serves every proc of the fight
+	e.procTargets = e.procTargets[:0]
+	for _, t := range targets {
+		e.procTargets = append(e.procTargets, t.CharacterID)
+	}
+	ev := EffectProcEvent{Turn: e.turn, OwnerID: owner.CharacterID, TargetIDs: e.procTargets, EffectID: eff.EffectID, Code: eff.CoreEffectCode, Trigger: trigger}
+	for _, o := range e.observers {
+		o.EffectProc(ev)
+	}
+}
+
+func (e *combatEngine) notifyDamage(source *combatant, target *combatant, amount int, absorbed int, cause string, effectID int) {
+	ev := DamageEvent{Turn: e.turn, TargetID: target.CharacterID, EffectID: effectID, Cause: cause, Amount: amount, Absorbed: absorbed, HPLeft: target.hp}
+	if source != nil {
+		ev.SourceID = source.CharacterID
+	}
+	for _, o := range e.observers {
+		o.DamageApplied(ev)
+	}
+}
+
+func (e *combatEngine) notifyHeal(source *combatant, target *combatant, amount int, overheal int, cause string, effectID int) {
+	ev := HealEvent{Turn: e.turn, SourceID: source.CharacterID, TargetID: target.CharacterID, EffectID: effectID, Cause: cause, Amount: amount, Overheal: overheal}
+	for _, o := range e.observers {
+		o.Heal(ev)
+	}
+}
+
+// notifySnapshot reports every fighter's state after actor's turn (nil for
+// the state the fight starts in, after on_start)
+func (e *combatEngine) notifySnapshot(actor *combatant) {
+	if !e.charting {
+		return
+	}
+	point := TimelinePoint{Turn: e.turn, Fighters: make([]FighterState, 0, len(e.teams[0])+len(e.teams[1]))}
+	if actor != nil {
+		point.ActorID = actor.CharacterID
+	}
+	for _, team := range e.teams {
+		for _, c := range team {
+			point.Fighters = append(point.Fighters, c.state())
+		}
+	}
+	for _, o := range e.observers {
+		o.Snapshot(point)
+	}
+}
+
+func (e *combatEngine) notifyDeath(killer *combatant, c *combatant, cause string) {
+	ev := DeathEvent{Turn: e.turn, CharacterID: c.CharacterID, Cause: cause}
+	if killer != nil {
+		ev.KillerID = killer.CharacterID
+	}
+	for _, o := range e.observers {
+		o.Death(ev)
+	}
+}
+
+// ── Fight metrics ───────────────────────────────────────────────────────────
+//
+// The bulk and build tournaments fight characters with IDs 1 (side A) and 2
+// (side B) whichever strikes first, so their metrics are keyed that way.
+
+// CombatMetrics accumulates what one fighter did over any number of fights
+type CombatMetrics struct {
+	Fights         int
+	Kills          int
+	KillTurns      int            // sum of the turns the kills happened on
+	DamageBySource map[string]int // HP damage dealt per DamageEvent cause
+}
+
+// CombatMetricsSummary is CombatMetrics averaged for storage and display
+type CombatMetricsSummary struct {
+	AvgTimeToKill  float64            `json:"avgTimeToKill,omitempty"`  // turns, over the fights won by a kill
+	DamageBySource map[string]float64 `json:"damageBySource,omitempty"` // per fight
+}
+
+func (m *CombatMetrics) summary() *CombatMetricsSummary {
+	if m.Fights == 0 {
+		return nil
+	}
+	s := &CombatMetricsSummary{DamageBySource: map[string]float64{}}
+	if m.Kills > 0 {
+		s.AvgTimeToKill = float64(m.KillTurns) / float64(m.Kills)
+	}
+	for cause, dmg := range m.DamageBySource {
+		s.DamageBySource[cause] = float64(dmg) / float64(m.Fights)
+	}
+	return s
+}
+
+// metricsObserver books damage and kills into side A's and side B's metrics
+type metricsObserver struct {
+	NopObserver
+	a, b *CombatMetrics
+}
+
+// matchObservers returns the observers a tournament match needs to fill in
+// side A's and side B's metrics, none if it collects neither. The caller
+// counts the fights (countFights).
+func matchObservers(metricsA, metricsB *CombatMetrics) []CombatObserver {
+	if metricsA == nil && metricsB == nil {
+		return nil
+	}
+	return []CombatObserver{&metricsObserver{a: metricsA, b: metricsB}}
+}
+
+// countFights adds n fights to every side that collects metrics
+func countFights(n int, metrics ...*CombatMetrics) {
+	for _, m := range metrics {
+		if m != nil {
+			m.Fights += n
+		}
+	}
+}
+
+// side returns the metrics of character ID 1 or 2, nil for anyone else
+func (o *metricsObserver) side(id int) *CombatMetrics {
+	switch id {
+	case 1:
+		return o.a
+	case 2:
+		return o.b
+	}
+	return nil
+}
+
+func (o *metricsObserver) DamageApplied(ev DamageEvent) {
+	m := o.side(ev.SourceID)
+	if m == nil || ev.Amount <= 0 || ev.SourceID == ev.TargetID {
+		return
+	}
+	if m.DamageBySource == nil {
+		m.DamageBySource = map[string]int{}
+	}
+	m.DamageBySource[ev.Cause] += ev.Amount
+}
+
+func (o *metricsObserver) Death(ev DeathEvent) {
+	if m := o.side(ev.KillerID); m != nil && ev.KillerID != ev.CharacterID {
+		m.Kills++
+		m.KillTurns += ev.Turn
+	}
+}
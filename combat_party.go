@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+)
+
+// ── Party combat ────────────────────────────────────────────────────────────
+//
+// The engine fights two teams of any size; a 1v1 fight (executeCombatWith)
+// is a party fight with one member per team. On its turn every living fighter
+// picks one living enemy by its team's targeting policy and attacks it; AoE
+// effects ignore the pick and land on the whole team. A team loses when all
+// of its members are down.
+
+// Targeting policies for CombatTeam.Targeting
+const (
+	targetRandom        = "random"         // any living enemy (default)
+	targetLowestHP      = "lowest_hp"      // the living enemy with the least HP
+	targetHighestThreat = "highest_threat" // the living enemy that has dealt the most damage and healing so far
+)
+
+var knownTargetingPolicies = map[string]bool{"": true, targetRandom: true, targetLowestHP: true, targetHighestThreat: true}
+
+// CombatTeam is one side of a party fight
+type CombatTeam struct {
+	Members   []*CombatCharacter
+	Targeting string
+}
+
+// PartyCombatRequest is the JSON body for /api/testPartyCombat
+type PartyCombatRequest struct {
+	Team1   PartyCombatTeam `json:"team1"`
+	Team2   PartyCombatTeam `json:"team2"`
+	Seed    *int64          `json:"seed,omitempty"`
+	RulesID *int64          `json:"rulesProfileId,omitempty"`
+}
+
+// PartyCombatTeam is one team of a party test request
+type PartyCombatTeam struct {
+	Members   []CombatTestCombatant `json:"members"`
+	Targeting string                `json:"targeting,omitempty"` // random (default), lowest_hp, highest_threat
+}
+
+// PartyCombatResult is the full outcome of one party fight
+type PartyCombatResult struct {
+	Header    PartyCombatHeader    `json:"header"`
+	Log       []CombatLogEntry     `json:"log"`
+	Stats     PartyCombatStats     `json:"stats"`
+	Timeline  []TimelinePoint      `json:"timeline"`
+	Breakdown PartyCombatBreakdown `json:"breakdown"`
+}
+
+// PartyCombatHeader holds the winning team, the seed and every member's summary
+type PartyCombatHeader struct {
+	WinnerTeam int                `json:"winnerTeam"` // 1 or 2
+	Seed       int64              `json:"seed"`
+	Turns      int                `json:"turns"`
+	TimedOut   bool               `json:"timedOut"` // nobody was wiped within the turn limit; winner decided on total HP
+	Team1      []CombatantSummary `json:"team1"`
+	Team2      []CombatantSummary `json:"team2"`
+}
+
+// PartyCombatStats are per-member stats, in the same order as the header summaries
+type PartyCombatStats struct {
+	Team1 []CombatStats `json:"team1"`
+	Team2 []CombatStats `json:"team2"`
+}
+
+// PartyCombatBreakdown holds every member's damage/heal breakdown, in header order
+type PartyCombatBreakdown struct {
+	Team1 []DamageBreakdown `json:"team1"`
+	Team2 []DamageBreakdown `json:"team2"`
+}
+
+// handleTestPartyCombat runs one N vs M fight
+func handleTestPartyCombat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req PartyCombatRequest
+	if err := decodeJSON(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Team1.Members) == 0 || len(req.Team2.Members) == 0 {
+		http.Error(w, "Both teams need at least one member", http.StatusBadRequest)
+		return
+	}
+	if !knownTargetingPolicies[req.Team1.Targeting] || !knownTargetingPolicies[req.Team2.Targeting] {
+		http.Error(w, "targeting must be random, lowest_hp or highest_threat", http.StatusBadRequest)
+		return
+	}
+
+	rules, err := loadCombatRules(req.RulesID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed := newCombatSeed()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	t1, t2, err := buildPartyTeams(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logCombatTest(rules, t1.Members, t2.Members)
+	result := executePartyCombat(t1, t2, CombatOptions{Seed: seed, Rules: rules})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// buildPartyTeams numbers the members 1..N for team 1 and on from there for team 2
+func buildPartyTeams(req *PartyCombatRequest) (CombatTeam, CombatTeam, error) {
+	var r testCombatantResolver
+	teams := [2]CombatTeam{{Targeting: req.Team1.Targeting}, {Targeting: req.Team2.Targeting}}
+	id := 1
+	for t, members := range [2][]CombatTestCombatant{req.Team1.Members, req.Team2.Members} {
+		for i, m := range members {
+			c, err := r.character(m, id)
+			if err != nil {
+				return CombatTeam{}, CombatTeam{}, fmt.Errorf("team%d member %d: %v", t+1, i+1, err)
+			}
+			teams[t].Members = append(teams[t].Members, c)
+			id++
+		}
+	}
+	return teams[0], teams[1], nil
+}
+
+// executePartyCombat runs one team fight drawing every roll from a generator
+// seeded with opts.Seed. Character IDs must be unique across both teams.
+func executePartyCombat(team1 CombatTeam, team2 CombatTeam, opts CombatOptions) *PartyCombatResult {
+	record := &combatLog{entries: []CombatLogEntry{}}
+	chart := newChartObserver()
+	e := &combatEngine{
+		rules:     opts.Rules,
+		rng:       rand.New(rand.NewSource(opts.Seed)),
+		targeting: [2]string{team1.Targeting, team2.Targeting},
+	}
+	e.watch(append([]CombatObserver{record, chart}, opts.Observers...)...)
+	for t, team := range [2]CombatTeam{team1, team2} {
+		for _, char := range team.Members {
+			c := newCombatant(char, opts.Rules)
+			c.team = t
+			e.teams[t] = append(e.teams[t], c)
+		}
+	}
+	e.orderFighters()
+
+	winner, timedOut := e.play()
+
+	result := &PartyCombatResult{
+		Header:   PartyCombatHeader{WinnerTeam: winner, Seed: opts.Seed, Turns: e.turn, TimedOut: timedOut},
+		Log:      record.entries,
+		Timeline: chart.timeline,
+	}
+	for _, c := range e.teams[0] {
+		result.Header.Team1 = append(result.Header.Team1, c.summary())
+		result.Stats.Team1 = append(result.Stats.Team1, *c.stats)
+		result.Breakdown.Team1 = append(result.Breakdown.Team1, chart.breakdownFor(c.CharacterID))
+	}
+	for _, c := range e.teams[1] {
+		result.Header.Team2 = append(result.Header.Team2, c.summary())
+		result.Stats.Team2 = append(result.Stats.Team2, *c.stats)
+		result.Breakdown.Team2 = append(result.Breakdown.Team2, chart.breakdownFor(c.CharacterID))
+	}
+	return result
+}
+
+// orderFighters sets the fixed acting order, which interleaves the teams: A1, B1, A2, B2, ...
+func (e *combatEngine) orderFighters() {
+	for i := 0; i < len(e.teams[0]) || i < len(e.teams[1]); i++ {
+		for t := range e.teams {
+			if i < len(e.teams[t]) {
+				e.fighters = append(e.fighters, e.teams[t][i])
+			}
+		}
+	}
+}
+
+// play applies passives, fires on_start and fights until a team is down or
+// the turn limit runs out, then returns the winning team (1 or 2) and
+// whether the fight timed out
+func (e *combatEngine) play() (int, bool) {
+	for _, c := range e.fighters {
+		e.applyPassives(c)
+	}
+	for _, team := range e.teams {
+		for _, c := range team {
+			e.fire("on_start", c, e.pickTarget(c), 0)
+		}
+	}
+	e.notifySnapshot(nil)
+
+	var winner int
+	if e.rules.Initiative == initiativeSpeed {
+		winner = e.runTimeline()
+	} else {
+		winner = e.runTurns()
+	}
+
+	// Timeout: highest total HP wins, team 1 on a tie
+	if winner != 0 {
+		return winner, false
+	}
+	e.turn = e.rules.MaxTurns
+	winner = 1
+	if teamHP(e.teams[1]) > teamHP(e.teams[0]) {
+		winner = 2
+	}
+	return winner, true
+}
+
+// pickTarget chooses the living enemy c attacks by its team's targeting
+// policy (nil if there is none). A lone enemy is picked without a roll.
+func (e *combatEngine) pickTarget(c *combatant) *combatant {
+	var last *combatant
+	count := 0
+	for _, o := range e.teams[1-c.team] {
+		if o.hp > 0 {
+			last = o
+			count++
+		}
+	}
+	if count <= 1 {
+		return last
+	}
+
+	alive := make([]*combatant, 0, count)
+	for _, o := range e.teams[1-c.team] {
+		if o.hp > 0 {
+			alive = append(alive, o)
+		}
+	}
+
+	best := alive[0]
+	switch e.targeting[c.team] {
+	case targetLowestHP:
+		for _, o := range alive[1:] {
+			if o.hp < best.hp {
+				best = o
+			}
+		}
+	case targetHighestThreat:
+		for _, o := range alive[1:] {
+			if o.threat() > best.threat() {
+				best = o
+			}
+		}
+	default:
+		best = alive[e.rng.Intn(len(alive))]
+	}
+	return best
+}
+
+// threat is how much a fighter has hurt its enemies and helped its allies so far
+func (c *combatant) threat() int {
+	return c.stats.DamageDealt + c.stats.HealingDone
+}
+
+// teamAlive reports whether any member of team t is still standing
+func (e *combatEngine) teamAlive(t int) bool {
+	for _, c := range e.teams[t] {
+		if c.hp > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// teamHP sums the remaining HP of a team's members; the dead count as 0
+func teamHP(team []*combatant) int {
+	total := 0
+	for _, c := range team {
+		if c.hp > 0 {
+			total += c.hp
+		}
+	}
+	return total
+}
+
+// logCombatTest prints the line a fight started from the tester opens with
+func logCombatTest(rules CombatRules, team1 []*CombatCharacter, team2 []*CombatCharacter) {
+	log.Printf("⚔️ Combat Test: %s vs %s", describeTeam(team1, rules), describeTeam(team2, rules))
+}
+
+func describeTeam(team []*CombatCharacter, rules CombatRules) string {
+	names := make([]string, len(team))
+	for i, char := range team {
+		_, hp := startingHP(char, rules)
+		names[i] = fmt.Sprintf("%s (%d HP)", char.CharacterName, hp)
+	}
+	return strings.Join(names, ", ")
+}
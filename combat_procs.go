@@ -0,0 +1,42 @@
+package main
+
+// ── Proc limits ─────────────────────────────────────────────────────────────
+//
+// A triggered effect whose condition holds still has to pass its own limits
+// before it procs: a cooldown in turns since its last proc, a cap on procs per
+// fight, and an internal proc chance rolled on top of whatever its value
+// means. A consumable procs once, whatever its other limits. Passive effects
+// are applied once and never go through here.
+
+// effectProcs is the proc bookkeeping of one of a combatant's effects
+type effectProcs struct {
+	count    int
+	lastTurn int
+}
+
+// tryProc checks effect i of c against its limits and, if it may proc,
+// records the proc and returns true. The proc chance is only rolled when set,
+// so effects without one leave the rng sequence untouched.
+func (e *combatEngine) tryProc(c *combatant, i int) bool {
+	eff := &c.Effects[i]
+	p := &c.procs[i]
+	if eff.MaxProcs > 0 && p.count >= eff.MaxProcs || eff.Consumable && p.count > 0 {
+		return false
+	}
+	if eff.Cooldown > 0 && p.count > 0 && e.turn < p.lastTurn+eff.Cooldown {
+		return false
+	}
+	if eff.ProcChance != nil && e.rng.Intn(100) >= *eff.ProcChance {
+		return false
+	}
+	p.count++
+	p.lastTurn = e.turn
+	if c.stats.Procs == nil {
+		c.stats.Procs = map[int]int{}
+	}
+	c.stats.Procs[eff.EffectID]++
+	if eff.Consumable {
+		e.consume(c, eff)
+	}
+	return true
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+)
+
+// ── Combat rules ────────────────────────────────────────────────────────────
+
+// CombatRules are the balance constants of the combat engine. Profiles are
+// stored in tooling.combat_rules so they can be tweaked without a rebuild.
+type CombatRules struct {
+	MaxTurns       int     `json:"maxTurns"`       // after this many turns the fight is decided on HP
+	HPPerStamina   int     `json:"hpPerStamina"`   // max HP = stamina × hpPerStamina
+	CritMultiplier int     `json:"critMultiplier"` // crit damage in % of a normal hit (200 = ×2)
+	ChanceScale    float64 `json:"chanceScale"`    // dodge/crit chance = chanceScale × log2(ratio+1) + bonus
+	ChanceMin      int     `json:"chanceMin"`      // lower clamp for dodge/crit chance
+	ChanceMax      int     `json:"chanceMax"`      // upper clamp for dodge/crit chance
+	ArmorConstant  int     `json:"armorConstant"`  // damage × K / (armor + K)
+	Initiative     string  `json:"initiative"`     // fixed, agility or speed (see combat_initiative.go)
+	StunDiminish   int     `json:"stunDiminish"`   // % of a stun's chance lost for every stun the target already took
+	StunImmunity   int     `json:"stunImmunity"`   // own turns a fighter can't be stunned after a stun wears off
+}
+
+// CombatRulesProfile is a named rules set (tooling.combat_rules)
+type CombatRulesProfile struct {
+	ProfileID   int64       `json:"profileId"`
+	Name        string      `json:"name"`
+	Description *string     `json:"description,omitempty"`
+	Rules       CombatRules `json:"rules"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+}
+
+// defaultCombatRules are the live game constants
+func defaultCombatRules() CombatRules {
+	return CombatRules{
+		MaxTurns:       30,
+		HPPerStamina:   10,
+		CritMultiplier: 200,
+		ChanceScale:    10,
+		ChanceMin:      1,
+		ChanceMax:      50,
+		ArmorConstant:  100,
+		Initiative:     initiativeFixed,
+	}
+}
+
+func (r CombatRules) validate() error {
+	switch {
+	case r.MaxTurns < 1:
+		return fmt.Errorf("maxTurns must be at least 1")
+	case r.HPPerStamina < 1:
+		return fmt.Errorf("hpPerStamina must be at least 1")
+	case r.CritMultiplier < 0:
+		return fmt.Errorf("critMultiplier must not be negative")
+	case r.ChanceMin < 0 || r.ChanceMax > 100 || r.ChanceMin > r.ChanceMax:
+		return fmt.Errorf("chanceMin/chanceMax must satisfy 0 <= min <= max <= 100")
+	case r.ArmorConstant < 1:
+		return fmt.Errorf("armorConstant must be at least 1")
+	case r.Initiative != initiativeFixed && r.Initiative != initiativeAgility && r.Initiative != initiativeSpeed:
+		return fmt.Errorf("initiative must be one of fixed, agility, speed")
+	case r.StunDiminish < 0 || r.StunDiminish > 100:
+		return fmt.Errorf("stunDiminish must be between 0 and 100")
+	case r.StunImmunity < 0:
+		return fmt.Errorf("stunImmunity must not be negative")
+	}
+	return nil
+}
+
+// parseCombatRules decodes a stored rules blob over the defaults, so keys
+// added after a profile was saved fall back to the live values
+func parseCombatRules(raw []byte) (CombatRules, error) {
+	rules := defaultCombatRules()
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return rules, err
+	}
+	return rules, rules.validate()
+}
+
+func (r CombatRules) maxHP(char *CombatCharacter) int {
+	return char.Stamina * r.HPPerStamina
+}
+
+// chance is a ratio-based chance with diminishing returns. With the default
+// curve: equal stats → 10%, double the stat → ~20%, triple → ~27%, clamped [1, 50]
+func (r CombatRules) chance(myStat int, theirStat int, bonusMod int) int {
+	denom := theirStat
+	if denom < 1 {
+		denom = 1
+	}
+	ratio := float64(myStat) / float64(denom)
+	chance := int(r.ChanceScale*math.Log2(ratio+1)) + bonusMod
+	if chance < r.ChanceMin {
+		chance = r.ChanceMin
+	}
+	if chance > r.ChanceMax {
+		chance = r.ChanceMax
+	}
+	return chance
+}
+
+// mitigate reduces damage by armor; any hit through armor deals at least 1
+func (r CombatRules) mitigate(damage int, armor int) int {
+	if armor <= 0 {
+		return damage
+	}
+	reduced := damage * r.ArmorConstant / (armor + r.ArmorConstant)
+	if reduced < 1 {
+		reduced = 1
+	}
+	return reduced
+}
+
+// rulesOrDefault reads the rules snapshot stored in a run config; runs
+// started before rules profiles existed have none and used the defaults
+func rulesOrDefault(r *CombatRules) CombatRules {
+	if r == nil {
+		return defaultCombatRules()
+	}
+	return *r
+}
+
+// loadCombatRules resolves an optional profile ID; nil means the defaults
+func loadCombatRules(profileID *int64) (CombatRules, error) {
+	if profileID == nil {
+		return defaultCombatRules(), nil
+	}
+	if db == nil {
+		return CombatRules{}, fmt.Errorf("database not available")
+	}
+	var raw []byte
+	err := db.QueryRow(`SELECT rules FROM tooling.combat_rules WHERE profile_id=$1`, *profileID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return CombatRules{}, fmt.Errorf("combat rules profile %d not found", *profileID)
+	}
+	if err != nil {
+		return CombatRules{}, err
+	}
+	return parseCombatRules(raw)
+}
+
+// ── CRUD: rules profiles ────────────────────────────────────────────────────
+
+// SaveCombatRulesRequest is the body for POST /api/saveCombatRules.
+type SaveCombatRulesRequest struct {
+	ProfileID   *int64      `json:"profileId,omitempty"` // nil = create
+	Name        string      `json:"name"`
+	Description *string     `json:"description,omitempty"`
+	Rules       CombatRules `json:"rules"`
+}
+
+func handleGetCombatRules(w http.ResponseWriter, r *http.Request) {
+	if db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+	rows, err := db.Query(`
+		SELECT profile_id, name, description, rules, created_at, updated_at
+		FROM tooling.combat_rules
+		ORDER BY name`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	profiles := []CombatRulesProfile{}
+	for rows.Next() {
+		var p CombatRulesProfile
+		var raw []byte
+		if err := rows.Scan(&p.ProfileID, &p.Name, &p.Description, &raw, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if p.Rules, err = parseCombatRules(raw); err != nil {
+			http.Error(w, fmt.Sprintf("profile %d: %v", p.ProfileID, err), http.StatusInternalServerError)
+			return
+		}
+		profiles = append(profiles, p)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"profiles": profiles,
+		"defaults": defaultCombatRules(),
+	})
+}
+
+func handleSaveCombatRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+	req := SaveCombatRulesRequest{Rules: defaultCombatRules()}
+	if err := decodeJSON(r, &req); err != nil {
+		http.Error(w, "Invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+	if err := req.Rules.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	raw, _ := json.Marshal(req.Rules)
+
+	var profileID int64
+	if req.ProfileID != nil {
+		profileID = *req.ProfileID
+		res, err := db.Exec(`
+			UPDATE tooling.combat_rules
+			SET name=$1, description=$2, rules=$3, updated_at=NOW()
+			WHERE profile_id=$4`,
+			req.Name, req.Description, raw, profileID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			http.Error(w, "profile not found", http.StatusNotFound)
+			return
+		}
+	} else {
+		if err := db.QueryRow(`
+			INSERT INTO tooling.combat_rules (name, description, rules)
+			VALUES ($1,$2,$3)
+			RETURNING profile_id`,
+			req.Name, req.Description, raw,
+		).Scan(&profileID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "profileId": profileID})
+}
+
+func handleDeleteCombatRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if db == nil {
+		http.Error(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+	var body struct {
+		ProfileID int64 `json:"profileId"`
+	}
+	if err := decodeJSON(r, &body); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	if _, err := db.Exec(`DELETE FROM tooling.combat_rules WHERE profile_id=$1`, body.ProfileID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
@@ -0,0 +1,76 @@
+package main
+
+import "math/rand"
+
+// ── Fast simulation ─────────────────────────────────────────────────────────
+//
+// Tournaments only need the winner of each fight. combatSimulator plays 1v1
+// fights without the combat log, the printed header or the result structs,
+// and keeps its generator, engine and fighters from one fight to the next so
+// a fight allocates next to nothing. For the same characters, rules and seed
+// it picks the same winner as executeCombatWith. Stats are still counted
+// (highest_threat targeting reads them) but never reported. The tournaments'
+// metrics observers read neither the log nor the timeline (ObserverNeeds), so
+// collecting metrics doesn't bring them back.
+
+type combatSimulator struct {
+	rules     CombatRules
+	rng       *rand.Rand
+	engine    combatEngine
+	fighters  [2]combatant
+	stats     [2]CombatStats
+	observers []CombatObserver
+}
+
+// newCombatSimulator prepares a simulator; observers (if any) watch every fight
+func newCombatSimulator(rules CombatRules, observers ...CombatObserver) *combatSimulator {
+	return &combatSimulator{rules: rules, rng: rand.New(rand.NewSource(1)), observers: observers}
+}
+
+// winner fights first against second, first acting first under fixed
+// initiative, and returns the winner's CharacterID
+func (s *combatSimulator) winner(first *CombatCharacter, second *CombatCharacter, seed int64) int {
+	s.rng.Seed(seed)
+	e := &s.engine
+	*e = combatEngine{
+		rules:       s.rules,
+		rng:         s.rng,
+		fighters:    e.fighters[:0],
+		teams:       [2][]*combatant{e.teams[0][:0], e.teams[1][:0]},
+		procTargets: e.procTargets[:0],
+	}
+	e.watch(s.observers...)
+	for t, char := range [2]*CombatCharacter{first, second} {
+		c := &s.fighters[t]
+		c.reset(char, s.rules, &s.stats[t])
+		c.team = t
+		e.teams[t] = append(e.teams[t], c)
+	}
+	e.orderFighters()
+
+	winner, _ := e.play()
+	return e.teams[winner-1][0].CharacterID
+}
+
+// reset readies a reused combatant for a new fight, keeping its buffers
+func (c *combatant) reset(char *CombatCharacter, rules CombatRules, stats *CombatStats) {
+	procs := c.procs[:0]
+	for range char.Effects {
+		procs = append(procs, effectProcs{})
+	}
+	procCounts := stats.Procs
+	clear(procCounts)
+	*stats = CombatStats{Procs: procCounts}
+
+	maxHP, hp := startingHP(char, rules)
+	*c = combatant{
+		CombatCharacter: char,
+		maxHP:           maxHP,
+		hp:              hp,
+		buffs:           c.buffs[:0],
+		dots:            c.dots[:0],
+		shields:         c.shields[:0],
+		procs:           procs,
+		stats:           stats,
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+)
+
+// ── Monte Carlo combat simulation ───────────────────────────────────────────
+
+const (
+	defaultSimulationFights = 200
+	maxSimulationFights     = 10000
+)
+
+// CombatSimulationRequest is a CombatTestRequest plus how many fights to run
+type CombatSimulationRequest struct {
+	CombatTestRequest
+	Fights int `json:"fights"`
+}
+
+// CombatSimulationResult summarises many seeded fights between the same two combatants
+type CombatSimulationResult struct {
+	Fights      int                `json:"fights"`
+	Seed        int64              `json:"seed"`
+	Timeouts    int                `json:"timeouts"`
+	TimeoutRate float64            `json:"timeoutRate"`
+	AvgTurns    float64            `json:"avgTurns"`
+	TurnCounts  map[int]int        `json:"turnCounts"` // fight length in turns → number of fights
+	Combatant1  SimulationSideStat `json:"combatant1"`
+	Combatant2  SimulationSideStat `json:"combatant2"`
+}
+
+// SimulationSideStat is one side's outcome over a simulation
+type SimulationSideStat struct {
+	ID             int             `json:"id"`
+	Name           string          `json:"name"`
+	MaxHP          int             `json:"maxHp"`
+	Wins           int             `json:"wins"`
+	WinRate        float64         `json:"winRate"`
+	WinRateLow     float64         `json:"winRateLow"`  // 95% Wilson interval
+	WinRateHigh    float64         `json:"winRateHigh"` // 95% Wilson interval
+	AvgHPRemaining float64         `json:"avgHpRemaining"`
+	AvgHPByTurn    []float64       `json:"avgHpByTurn"`  // HP at the end of each turn (index 0: start), finished fights keep their final HP
+	Stats          CombatStats     `json:"stats"`        // summed over all fights
+	AvgStats       CombatStats     `json:"avgStats"`     // Stats / fights, rounded
+	Breakdown      DamageBreakdown `json:"breakdown"`    // summed over all fights
+	AvgBreakdown   DamageBreakdown `json:"avgBreakdown"` // Breakdown / fights, rounded
+}
+
+func handleSimulateCombat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req CombatSimulationRequest
+	if err := decodeJSON(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Fights <= 0 {
+		req.Fights = defaultSimulationFights
+	}
+	if req.Fights > maxSimulationFights {
+		http.Error(w, fmt.Sprintf("fights must be at most %d", maxSimulationFights), http.StatusBadRequest)
+		return
+	}
+
+	rules, err := loadCombatRules(req.RulesID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed := newCombatSeed()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	c1, c2, err := buildTestCombatants(&req.CombatTestRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result := simulateCombat(c1, c2, req.Fights, CombatOptions{Seed: seed, Rules: rules})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// simulateCombat runs `fights` fights, alternating who strikes first like
+// runMatch does. Each fight gets its own seed drawn from opts.Seed.
+func simulateCombat(c1 *CombatCharacter, c2 *CombatCharacter, fights int, opts CombatOptions) *CombatSimulationResult {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	out := &CombatSimulationResult{
+		Fights:     fights,
+		Seed:       opts.Seed,
+		TurnCounts: map[int]int{},
+		Combatant1: SimulationSideStat{ID: c1.CharacterID, Name: c1.CharacterName, MaxHP: opts.Rules.maxHP(c1)},
+		Combatant2: SimulationSideStat{ID: c2.CharacterID, Name: c2.CharacterName, MaxHP: opts.Rules.maxHP(c2)},
+	}
+	hpSum1, hpSum2, turnSum := 0, 0, 0
+	var curve1, curve2 hpCurve
+
+	for i := 0; i < fights; i++ {
+		fightOpts := opts
+		fightOpts.Seed = rng.Int63()
+		var result *CombatResult
+		if i%2 == 0 {
+			result = executeCombatWith(c1, c2, fightOpts)
+		} else {
+			result = executeCombatWith(c2, c1, fightOpts)
+		}
+
+		switch result.Header.WinnerID {
+		case c1.CharacterID:
+			out.Combatant1.Wins++
+		case c2.CharacterID:
+			out.Combatant2.Wins++
+		}
+		if result.Header.TimedOut {
+			out.Timeouts++
+		}
+		out.TurnCounts[result.Header.Turns]++
+		turnSum += result.Header.Turns
+		hpSum1 += result.SummaryFor(c1.CharacterID).HPEnd
+		hpSum2 += result.SummaryFor(c2.CharacterID).HPEnd
+		out.Combatant1.Stats.Add(*result.StatsFor(c1.CharacterID))
+		out.Combatant2.Stats.Add(*result.StatsFor(c2.CharacterID))
+		out.Combatant1.Breakdown.Add(result.BreakdownFor(c1.CharacterID))
+		out.Combatant2.Breakdown.Add(result.BreakdownFor(c2.CharacterID))
+		curve1.add(result.Timeline, c1.CharacterID)
+		curve2.add(result.Timeline, c2.CharacterID)
+	}
+
+	if fights == 0 {
+		return out
+	}
+	n := float64(fights)
+	out.TimeoutRate = float64(out.Timeouts) / n
+	out.AvgTurns = float64(turnSum) / n
+	out.Combatant1.finish(hpSum1, fights)
+	out.Combatant2.finish(hpSum2, fights)
+	out.Combatant1.AvgHPByTurn = curve1.average(fights)
+	out.Combatant2.AvgHPByTurn = curve2.average(fights)
+	return out
+}
+
+// hpCurve sums one side's HP per turn over many fights. A fight that ended
+// keeps counting with its final HP for every later turn.
+type hpCurve struct {
+	sums  []int // HP summed per turn over the fights still going
+	ended []int // final HP of the fights that ended, at the first turn they miss
+}
+
+// add books one fight's timeline; a turn's HP is the one after its last half-turn
+func (c *hpCurve) add(timeline []TimelinePoint, id int) {
+	if len(timeline) == 0 {
+		return
+	}
+	hp, turn := 0, timeline[0].Turn
+	for _, p := range timeline {
+		for ; turn < p.Turn; turn++ {
+			c.sums = growTo(c.sums, turn)
+			c.sums[turn] += hp
+		}
+		hp = p.hpOf(id)
+	}
+	c.sums = growTo(c.sums, turn)
+	c.sums[turn] += hp
+	c.ended = growTo(c.ended, turn+1)
+	c.ended[turn+1] += hp
+}
+
+// average is the per-turn mean HP over n fights
+func (c *hpCurve) average(n int) []float64 {
+	out := make([]float64, len(c.sums))
+	carried := 0
+	for t, sum := range c.sums {
+		if t < len(c.ended) {
+			carried += c.ended[t]
+		}
+		out[t] = float64(sum+carried) / float64(n)
+	}
+	return out
+}
+
+// growTo extends s with zeros so that s[i] exists
+func growTo(s []int, i int) []int {
+	for len(s) <= i {
+		s = append(s, 0)
+	}
+	return s
+}
+
+// finish fills the rates and averages once all fights are in
+func (s *SimulationSideStat) finish(hpSum int, fights int) {
+	n := float64(fights)
+	s.WinRate = float64(s.Wins) / n
+	s.WinRateLow, s.WinRateHigh = wilsonInterval(s.Wins, fights)
+	s.AvgHPRemaining = float64(hpSum) / n
+
+	avg := func(v int) int { return int(math.Round(float64(v) / n)) }
+	s.AvgStats = CombatStats{
+		DamageDealt:      avg(s.Stats.DamageDealt),
+		DamageTaken:      avg(s.Stats.DamageTaken),
+		HealingDone:      avg(s.Stats.HealingDone),
+		Overheal:         avg(s.Stats.Overheal),
+		HealingBlocked:   avg(s.Stats.HealingBlocked),
+		Attacks:          avg(s.Stats.Attacks),
+		CritHits:         avg(s.Stats.CritHits),
+		DodgedAttacks:    avg(s.Stats.DodgedAttacks),
+		AttacksDodged:    avg(s.Stats.AttacksDodged),
+		StunApplied:      avg(s.Stats.StunApplied),
+		TimesStunned:     avg(s.Stats.TimesStunned),
+		StunsResisted:    avg(s.Stats.StunsResisted),
+		StunsImmune:      avg(s.Stats.StunsImmune),
+		BleedApplied:     avg(s.Stats.BleedApplied),
+		PoisonApplied:    avg(s.Stats.PoisonApplied),
+		BurnApplied:      avg(s.Stats.BurnApplied),
+		CounterHits:      avg(s.Stats.CounterHits),
+		DoubleAttacks:    avg(s.Stats.DoubleAttacks),
+		MaxConsecHits:    s.Stats.MaxConsecHits,
+		ShieldAbsorbed:   avg(s.Stats.ShieldAbsorbed),
+		LifestealHealing: avg(s.Stats.LifestealHealing),
+		DamageReflected:  avg(s.Stats.DamageReflected),
+		DebuffsCleansed:  avg(s.Stats.DebuffsCleansed),
+		BuffsDispelled:   avg(s.Stats.BuffsDispelled),
+		PotionsUsed:      avg(s.Stats.PotionsUsed),
+	}
+	s.AvgBreakdown = s.Breakdown.average(fights)
+	for id, n := range s.Stats.Procs {
+		if s.AvgStats.Procs == nil {
+			s.AvgStats.Procs = map[int]int{}
+		}
+		s.AvgStats.Procs[id] = avg(n)
+	}
+}
+
+// wilsonInterval is the 95% Wilson score interval for wins out of n
+func wilsonInterval(wins int, n int) (float64, float64) {
+	if n == 0 {
+		return 0, 0
+	}
+	const z = 1.96
+	nf := float64(n)
+	p := float64(wins) / nf
+	denom := 1 + z*z/nf
+	center := (p + z*z/(2*nf)) / denom
+	half := z * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf)) / denom
+	return math.Max(0, center-half), math.Min(1, center+half)
+}
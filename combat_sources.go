@@ -0,0 +1,273 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ── Test combatant sources ──────────────────────────────────────────────────
+//
+// A side of a test fight either brings its own stats and effects, plus any
+// talent picks the server resolves into more effects, or references a
+// stored character, which the server assembles the same way
+// the game and the build tournaments do: a game.enemies row or a pending
+// tooling.enemies row with all of its talent points spent, or a
+// tooling.builds row as snapshotBuild sees it on BuildDay. Either kind can
+// bring equipment, blessings and potions; a build uses the request's instead
+// of its own where the request has any.
+
+// buildCareerDays is the length of a build's career, the day a build is
+// snapshotted at when the request doesn't pick one
+const buildCareerDays = 70
+
+// Limits on hand-made combatants: far beyond anything the game reaches, yet
+// low enough that no damage, heal or HP formula overflows
+const (
+	maxTestStat        = 100000
+	maxTestEffectValue = 100000
+	maxTestEffects     = 64
+)
+
+// testCombatantResolver assembles test combatants. The talent, effect, perk
+// and item tables are loaded once, and only if a combatant needs the DB.
+type testCombatantResolver struct {
+	loaded  bool
+	talents map[int]TalentInfo
+	effects map[int]Effect
+	perks   map[int]Perk
+	items   map[int]Item
+}
+
+// buildTestCombatants turns both sides of a test request into combat characters
+func buildTestCombatants(req *CombatTestRequest) (*CombatCharacter, *CombatCharacter, error) {
+	var r testCombatantResolver
+	c1, err := r.character(req.Combatant1, 1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("combatant1: %v", err)
+	}
+	c2, err := r.character(req.Combatant2, 2)
+	if err != nil {
+		return nil, nil, fmt.Errorf("combatant2: %v", err)
+	}
+	return c1, c2, nil
+}
+
+// character assembles one combatant with the given character ID
+func (r *testCombatantResolver) character(c CombatTestCombatant, id int) (*CombatCharacter, error) {
+	refs := 0
+	for _, set := range []bool{c.EnemyID != nil, c.PendingEnemyID != nil, c.BuildID != nil} {
+		if set {
+			refs++
+		}
+	}
+	if refs == 0 {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if refs == 0 && len(c.Talents) == 0 && len(c.Equipment) == 0 && len(c.Blessings) == 0 && len(c.Potions) == 0 {
+		return c.character(id), nil
+	}
+	if refs > 1 {
+		return nil, fmt.Errorf("set at most one of enemyId, pendingEnemyId and buildId")
+	}
+	if c.BuildDay < 0 {
+		return nil, fmt.Errorf("buildDay must not be negative")
+	}
+	if db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	if err := validateLoadout(c.Equipment, r.items); err != nil {
+		return nil, err
+	}
+	if err := validateBlessings(c.Blessings, r.perks); err != nil {
+		return nil, err
+	}
+	if err := validatePotions(c.Potions, r.items); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c.EnemyID != nil:
+		e, err := getGameEnemy(*c.EnemyID)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("enemy %d not found", *c.EnemyID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return r.outfit(r.enemy(id, e.EnemyName, e.Strength, e.Stamina, e.Agility, e.Luck, e.Armor, e.MinDamage, e.MaxDamage, e.Talents), c), nil
+
+	case c.PendingEnemyID != nil:
+		e, err := getPendingEnemy(*c.PendingEnemyID)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("pending enemy %d not found", *c.PendingEnemyID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return r.outfit(r.enemy(id, e.EnemyName, e.Strength, e.Stamina, e.Agility, e.Luck, e.Armor, e.MinDamage, e.MaxDamage, e.Talents), c), nil
+
+	case c.BuildID == nil:
+		return r.outfit(r.custom(c, id), c), nil
+	}
+
+	b, err := loadBuild(*c.BuildID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("build %d not found", *c.BuildID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	day := c.BuildDay
+	if day == 0 {
+		day = buildCareerDays
+	}
+	if len(c.Equipment) > 0 {
+		b.Equipment = c.Equipment
+	}
+	if len(c.Blessings) > 0 {
+		b.Blessings = c.Blessings
+	}
+	if len(c.Potions) > 0 {
+		b.Potions = c.Potions
+	}
+	return snapshotBuild(id, b, day, r.talents, r.effects, r.perks, r.items), nil
+}
+
+// validate rejects hand-made stats and effects the engine can't make sense
+// of: no HP, negative stats, an inverted damage range, negative amounts for
+// effects that deal, heal or shield, and values past the limits above
+func (c CombatTestCombatant) validate() error {
+	if c.Stamina < 1 || c.Stamina > maxTestStat {
+		return fmt.Errorf("stamina must be between 1 and %d", maxTestStat)
+	}
+	for _, stat := range []struct {
+		name  string
+		value int
+	}{
+		{"strength", c.Strength}, {"agility", c.Agility}, {"luck", c.Luck}, {"armor", c.Armor},
+		{"minDamage", c.MinDamage}, {"maxDamage", c.MaxDamage},
+	} {
+		if stat.value < 0 || stat.value > maxTestStat {
+			return fmt.Errorf("%s must be between 0 and %d", stat.name, maxTestStat)
+		}
+	}
+	if c.MinDamage > c.MaxDamage {
+		return fmt.Errorf("minDamage must not exceed maxDamage")
+	}
+	if len(c.Effects) > maxTestEffects {
+		return fmt.Errorf("at most %d effects", maxTestEffects)
+	}
+	for i := range c.Effects {
+		if err := c.Effects[i].validate(); err != nil {
+			return fmt.Errorf("effect %d: %v", c.Effects[i].EffectID, err)
+		}
+	}
+	for _, t := range c.Talents {
+		if t.Points < 0 {
+			return fmt.Errorf("talent %d: points must not be negative", t.TalentID)
+		}
+	}
+	return nil
+}
+
+// validate rejects effect values the engine can't make sense of. Modifiers
+// may be negative (debuffs); unknown codes and triggers are left to the
+// support report.
+func (eff *CombatTestEffect) validate() error {
+	if eff.Value < -maxTestEffectValue || eff.Value > maxTestEffectValue {
+		return fmt.Errorf("value must be between %d and %d", -maxTestEffectValue, maxTestEffectValue)
+	}
+	if h := effectHandlers[eff.CoreEffectCode]; h != nil && h.usesFactor && eff.Value < 0 {
+		return fmt.Errorf("%s value must not be negative", eff.CoreEffectCode)
+	}
+	if eff.Duration != nil && *eff.Duration < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+	if eff.Cooldown < 0 || eff.MaxProcs < 0 || eff.MaxStacks < 0 || eff.Decay < 0 {
+		return fmt.Errorf("cooldown, maxProcs, maxStacks and decay must not be negative")
+	}
+	if eff.ProcChance != nil && (*eff.ProcChance < 0 || *eff.ProcChance > 100) {
+		return fmt.Errorf("procChance must be between 0 and 100")
+	}
+	return nil
+}
+
+// outfit gives a character assembled by the resolver the request's
+// equipment, blessings and potions
+func (r *testCombatantResolver) outfit(ch *CombatCharacter, c CombatTestCombatant) *CombatCharacter {
+	equipCharacter(ch, c.Equipment, r.items, r.effects)
+	blessCharacter(ch, c.Blessings, r.perks, r.effects)
+	stockPotions(ch, c.Potions, r.items, r.effects)
+	return ch
+}
+
+// custom assembles a hand-made combatant, its talent picks' effects numbered
+// after its own
+func (r *testCombatantResolver) custom(c CombatTestCombatant, id int) *CombatCharacter {
+	ch := c.character(id)
+	if len(c.Talents) == 0 {
+		return ch
+	}
+	points, last := 0, 0
+	for _, t := range c.Talents {
+		points += t.Points
+	}
+	for _, eff := range c.Effects {
+		if eff.EffectID > last {
+			last = eff.EffectID
+		}
+	}
+	ch.Effects = append([]CombatTestEffect(nil), c.Effects...)
+	for _, eff := range talentEffects(c.Talents, points, r.talents, r.effects, r.perks) {
+		eff.EffectID += last
+		ch.Effects = append(ch.Effects, eff)
+	}
+	return ch
+}
+
+// enemy assembles an enemy row with every talent point it has spent
+func (r *testCombatantResolver) enemy(id int, name string, str, sta, agi, lck, arm, minDmg, maxDmg int, owned []EnemyTalent) *CombatCharacter {
+	talents := make([]BuildTalent, len(owned))
+	points := 0
+	for i, t := range owned {
+		talents[i] = BuildTalent{TalentID: t.TalentID, Points: t.Points, TalentOrder: t.TalentOrder, PerkID: t.PerkID}
+		points += t.Points
+	}
+	c := &CombatCharacter{
+		CharacterID:   id,
+		CharacterName: name,
+		Strength:      str,
+		Stamina:       sta,
+		Agility:       agi,
+		Luck:          lck,
+		Armor:         arm,
+		MinDamage:     minDmg,
+		MaxDamage:     maxDmg,
+		Effects:       talentEffects(talents, points, r.talents, r.effects, r.perks),
+	}
+	if c.Stamina < 1 {
+		c.Stamina = 1
+	}
+	return c
+}
+
+func (r *testCombatantResolver) load() error {
+	if r.loaded {
+		return nil
+	}
+	talents, effects, perks, err := loadBuildLookups()
+	if err != nil {
+		return fmt.Errorf("error loading talents, effects and perks: %v", err)
+	}
+	items, err := loadItemLookup()
+	if err != nil {
+		return fmt.Errorf("error loading items: %v", err)
+	}
+	r.talents, r.effects, r.perks, r.items, r.loaded = talents, effects, perks, items, true
+	return nil
+}
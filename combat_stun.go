@@ -0,0 +1,95 @@
+package main
+
+// ── Stuns ───────────────────────────────────────────────────────────────────
+//
+// A stun makes its target skip its next Duration turns (one without a
+// duration). The effect's Value is the proc chance; a successful roll can
+// still be resisted: the target's stun_resist takes points off the chance,
+// and every stun it already took this fight cuts what is left by
+// rules.StunDiminish percent. After a stun wears off the target can't be
+// stunned again for rules.StunImmunity of its own turns. Both rules default
+// to 0, which is the live behaviour.
+
+// applyStunEffect rolls the stun and, if it lands, stuns the target
+func applyStunEffect(e *combatEngine, ctx *effectContext) {
+	roll := e.rng.Intn(100)
+	if roll >= ctx.effect.Value {
+		return
+	}
+	target := ctx.target
+	if target.stunImmune > 0 {
+		target.stats.StunsImmune++
+		if e.logging {
+			e.emit(e.effectLogEntry(ctx, "stun_immune", 0))
+		}
+		return
+	}
+	if roll >= e.stunChance(ctx.effect.Value, target) {
+		target.stats.StunsResisted++
+		if e.logging {
+			e.emit(e.effectLogEntry(ctx, "stun_resisted", 0))
+		}
+		return
+	}
+
+	turns := 1
+	if ctx.effect.Duration != nil && *ctx.effect.Duration > 1 {
+		turns = *ctx.effect.Duration
+	}
+	// A stun on a stunned target never shortens the one it already has
+	if turns > target.stunTurns {
+		target.stunTurns = turns
+	}
+	target.stunsTaken++
+	ctx.source.stats.StunApplied++
+	if e.logging {
+		entry := e.effectLogEntry(ctx, "stun", 0)
+		if turns > 1 {
+			entry.Duration = &turns
+		}
+		e.emit(entry)
+	}
+}
+
+// stunChance is a stun's chance against target after stun_resist and diminishing returns
+func (e *combatEngine) stunChance(base int, target *combatant) int {
+	chance := base - target.mods.StunResist
+	for i := 0; i < target.stunsTaken && chance > 0; i++ {
+		chance = chance * (100 - e.rules.StunDiminish) / 100
+	}
+	return chance
+}
+
+// loseStunnedTurn spends one of c's stunned turns, reporting whether c is
+// stunned this turn. The last stunned turn opens the immunity window.
+func (e *combatEngine) loseStunnedTurn(c *combatant) bool {
+	if c.stunTurns == 0 {
+		return false
+	}
+	c.stunTurns--
+	c.consecHits = 0 // stun breaks consecutive hits
+	c.stats.TimesStunned++
+	if e.logging {
+		e.emit(CombatLogEntry{Turn: e.turn, CharacterID: c.CharacterID, Action: "stunned", Factor: 0})
+	}
+
+	if c.stunTurns == 0 && e.rules.StunImmunity > 0 {
+		c.stunImmune = e.rules.StunImmunity
+		if e.logging {
+			dur := c.stunImmune
+			e.emit(CombatLogEntry{Turn: e.turn, CharacterID: c.CharacterID, Action: "stun_immunity", Duration: &dur})
+		}
+	}
+	return true
+}
+
+// tickStunImmunity counts down c's immunity window at the end of a turn c acted in
+func (e *combatEngine) tickStunImmunity(c *combatant) {
+	if c.stunImmune == 0 {
+		return
+	}
+	c.stunImmune--
+	if c.stunImmune == 0 && e.logging {
+		e.emit(CombatLogEntry{Turn: e.turn, CharacterID: c.CharacterID, Action: "buff_expire", BuffType: "stun_immunity"})
+	}
+}
@@ -2,7 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -22,16 +28,17 @@ func baseCombatant(id int, name string, effects []CombatTestEffect) *CombatChara
 	}
 }
 
-func extractLog(result map[string]interface{}) []CombatLogEntry {
-	raw, _ := json.Marshal(result["log"])
-	var log []CombatLogEntry
-	json.Unmarshal(raw, &log)
-	return log
+func extractLog(result *CombatResult) []CombatLogEntry {
+	return result.Log
 }
 
-func extractStats(result map[string]interface{}, side string) map[string]interface{} {
-	stats := result["stats"].(map[string]interface{})
-	raw, _ := json.Marshal(stats[side])
+// extractStats returns one side's stats as a generic map (keyed by JSON name)
+func extractStats(result *CombatResult, side string) map[string]interface{} {
+	stats := result.Stats.Combatant1
+	if side == "combatant2" {
+		stats = result.Stats.Combatant2
+	}
+	raw, _ := json.Marshal(stats)
 	var s map[string]interface{}
 	json.Unmarshal(raw, &s)
 	return s
@@ -4055,9 +4062,9 @@ func TestPerkEvasiveCounter(t *testing.T) {
 // ════════════════════════════════════════════════════════════════════════════
 
 // helper: run N combats, return (wins1, wins2, draws, all results)
-func runManyCombats(c1 *CombatCharacter, c2 *CombatCharacter, n int) (int, int, int, []map[string]interface{}) {
+func runManyCombats(c1 *CombatCharacter, c2 *CombatCharacter, n int) (int, int, int, []*CombatResult) {
 	wins1, wins2, draws := 0, 0, 0
-	results := make([]map[string]interface{}, 0, n)
+	results := make([]*CombatResult, 0, n)
 	for i := 0; i < n; i++ {
 		// Deep-copy effects so repeated runs don't share state
 		eff1 := make([]CombatTestEffect, len(c1.Effects))
@@ -4080,23 +4087,12 @@ func runManyCombats(c1 *CombatCharacter, c2 *CombatCharacter, n int) (int, int,
 
 		result := executeCombat(a, b)
 		results = append(results, result)
-		header, _ := result["header"].(map[string]interface{})
-		if header != nil {
-			winnerID := 0
-			if wid, ok := header["winnerId"].(int); ok {
-				winnerID = wid
-			} else if wid, ok := header["winnerId"].(float64); ok {
-				winnerID = int(wid)
-			}
-			switch winnerID {
-			case a.CharacterID:
-				wins1++
-			case b.CharacterID:
-				wins2++
-			default:
-				draws++
-			}
-		} else {
+		switch result.Header.WinnerID {
+		case a.CharacterID:
+			wins1++
+		case b.CharacterID:
+			wins2++
+		default:
 			draws++
 		}
 	}
@@ -4104,7 +4100,7 @@ func runManyCombats(c1 *CombatCharacter, c2 *CombatCharacter, n int) (int, int,
 }
 
 // helper: aggregate stats over many results
-func aggregateStats(results []map[string]interface{}, side string) map[string]float64 {
+func aggregateStats(results []*CombatResult, side string) map[string]float64 {
 	agg := map[string]float64{}
 	for _, r := range results {
 		s := extractStats(r, side)
@@ -4704,13 +4700,8 @@ func TestFullBuild_StressTest(t *testing.T) {
 
 	// Verify all combats produced valid results
 	for i, r := range results {
-		header, ok := r["header"].(map[string]interface{})
-		if !ok || header == nil {
-			t.Errorf("Combat %d: missing header", i)
-			continue
-		}
-		if _, ok := header["winnerId"]; !ok {
-			t.Errorf("Combat %d: missing winnerId in header", i)
+		if r.Header.WinnerID != c1.CharacterID && r.Header.WinnerID != c2.CharacterID {
+			t.Errorf("Combat %d: winnerId %d is neither combatant", i, r.Header.WinnerID)
 		}
 		log := extractLog(r)
 		if len(log) == 0 {
@@ -4722,6 +4713,2124 @@ func TestFullBuild_StressTest(t *testing.T) {
 	fmt.Printf("    All %d combats completed without panics ✓\n", N)
 }
 
-// helper for condition pointers
-func strPtr(s string) *string { return &s }
-func intPtr(i int) *int       { return &i }
+// ── Test 121: Seeded combat is reproducible ──────────────────────
+// Same combatants + same seed must produce a byte-identical result.
+func TestSeededCombatReplay(t *testing.T) {
+	dur := 2
+	effects1 := []CombatTestEffect{
+		{EffectID: 113, CoreEffectCode: "bleed", TriggerType: "on_crit", FactorType: "percent_of_max_hp", Value: 3},
+		{EffectID: 136, CoreEffectCode: "stun", TriggerType: "on_hit", FactorType: "percent", Value: 20},
+	}
+	effects2 := []CombatTestEffect{
+		{EffectID: 131, CoreEffectCode: "counterattack", TriggerType: "on_crit_taken", FactorType: "percent", Value: 30, TargetSelf: true, Duration: &dur},
+		{EffectID: 1, CoreEffectCode: "dodge", TriggerType: "passive", FactorType: "percent", Value: 15},
+	}
+
+	const seed = 424242
+	first := executeCombatSeeded(baseCombatant(1, "A", effects1), baseCombatant(2, "B", effects2), seed)
+	second := executeCombatSeeded(baseCombatant(1, "A", effects1), baseCombatant(2, "B", effects2), seed)
+
+	raw1, _ := json.Marshal(first)
+	raw2, _ := json.Marshal(second)
+	if string(raw1) != string(raw2) {
+		t.Fatalf("Same seed produced different results:\n%s\n%s", raw1, raw2)
+	}
+
+	if first.Header.Seed != seed {
+		t.Errorf("Header seed: got %d, want %d", first.Header.Seed, seed)
+	}
+
+	// A different seed should (practically always) diverge somewhere in the log.
+	other := executeCombatSeeded(baseCombatant(1, "A", effects1), baseCombatant(2, "B", effects2), seed+1)
+	raw3, _ := json.Marshal(other.Log)
+	rawLog1, _ := json.Marshal(first.Log)
+	if string(raw3) == string(rawLog1) {
+		t.Error("Different seeds produced identical logs")
+	}
+}
+
+// ── Test 122: Stun and bleed work on every trigger ──────────────────────
+
+func TestStunAndBleedOnEveryTrigger(t *testing.T) {
+	for _, trigger := range combatTriggers {
+		c1 := baseCombatant(1, "Source", []CombatTestEffect{
+			{EffectID: 901, CoreEffectCode: "stun", TriggerType: trigger, FactorType: "percent", Value: 100},
+			{EffectID: 902, CoreEffectCode: "bleed", TriggerType: trigger, FactorType: "percent", Value: 2},
+			{EffectID: 903, CoreEffectCode: "crit", TriggerType: "passive", FactorType: "percent", Value: 40},
+		})
+		c1.Stamina = 50
+		// Both sides crit half the time so the *_taken triggers fire on c1 too
+		c2 := baseCombatant(2, "Target", []CombatTestEffect{
+			{EffectID: 903, CoreEffectCode: "crit", TriggerType: "passive", FactorType: "percent", Value: 40},
+		})
+		c2.Stamina = 50
+		result := executeCombatSeeded(c1, c2, 7)
+
+		stuns, bleeds := 0, 0
+		for _, e := range extractLog(result) {
+			if e.EffectID == nil || e.TriggerType != trigger {
+				continue
+			}
+			switch {
+			case *e.EffectID == 901 && e.Action == "stun":
+				stuns++
+			case *e.EffectID == 902 && e.Action == "bleed":
+				bleeds++
+			}
+		}
+		if stuns == 0 {
+			t.Errorf("%s: stun never applied", trigger)
+		}
+		if bleeds == 0 {
+			t.Errorf("%s: bleed never applied", trigger)
+		}
+	}
+}
+
+// ── Test 123: No declared (code, trigger) pair is a no-op ──────────────────────
+
+// probeEffect builds an effect that is guaranteed to change some fighter's state if it fires
+func probeEffect(code string, trigger string) CombatTestEffect {
+	h := effectHandlers[code]
+	eff := CombatTestEffect{EffectID: 999, CoreEffectCode: code, TriggerType: trigger, FactorType: "percent", Value: 100}
+	if h.usesDuration {
+		dur := 1
+		eff.Value = 10
+		eff.Duration = &dur
+		eff.TargetSelf = true
+	}
+	if code == "heal" {
+		eff.TargetSelf = true
+	}
+	if code == "stun" {
+		eff.Value = 100
+	}
+	return eff
+}
+
+// probeState is what an effect can change on a fighter
+type probeState struct {
+	hp, buffs, dots, shields, stunTurns int
+	mods                                CombatModifiers
+}
+
+func probeStateOf(c *combatant) probeState {
+	return probeState{hp: c.hp, buffs: len(c.buffs), dots: len(c.dots), shields: len(c.shields), stunTurns: c.stunTurns, mods: c.mods}
+}
+
+func TestEffectSupportMatrixHasNoNoOps(t *testing.T) {
+	for code, h := range effectHandlers {
+		if h.passive {
+			c := newCombatant(baseCombatant(1, "Passive", []CombatTestEffect{
+				{EffectID: 999, CoreEffectCode: code, TriggerType: "passive", FactorType: "percent", Value: 10, TargetSelf: true},
+			}), defaultCombatRules())
+			(&combatEngine{}).applyPassives(c)
+			if c.mods == (CombatModifiers{}) && len(c.shields) == 0 {
+				t.Errorf("%s/passive is declared supported but changes no modifier or shield", code)
+			}
+		}
+
+		for _, trigger := range combatTriggers {
+			if !h.supportsTrigger(trigger) {
+				continue
+			}
+			// Both sides are hurt and carry a buff, a debuff and a bleed, so
+			// heals, cleanses and dispels have something to work on
+			e := &combatEngine{rules: defaultCombatRules(), rng: rand.New(rand.NewSource(1))}
+			prober := newCombatant(baseCombatant(1, "Prober", []CombatTestEffect{probeEffect(code, trigger)}), e.rules)
+			dummy := newCombatant(baseCombatant(2, "Dummy", nil), e.rules)
+			dummy.team = 1
+			e.teams = [2][]*combatant{{prober}, {dummy}}
+			for _, c := range []*combatant{prober, dummy} {
+				c.hp = c.maxHP / 2
+				c.buffs = []TempBuff{{ModifierType: "modify_armor", Value: 5, Remaining: 3}, {ModifierType: "modify_armor", Value: -5, Remaining: 3}}
+				c.dots = []dotStack{{kind: "bleed", damage: 3, remaining: 3, source: c}}
+			}
+			before := [2]probeState{probeStateOf(prober), probeStateOf(dummy)}
+			e.fire(trigger, prober, dummy, 20)
+			if after := [2]probeState{probeStateOf(prober), probeStateOf(dummy)}; after == before {
+				t.Errorf("%s/%s is declared supported but changed no HP, buff, DoT, shield, stun or modifier", code, trigger)
+			}
+		}
+	}
+}
+
+// ── Test 124: Support report flags ignored combinations ──────────────────────
+
+func TestCheckEffectSupport(t *testing.T) {
+	dur := 2
+	cases := []struct {
+		eff    CombatTestEffect
+		status string
+	}{
+		{CombatTestEffect{CoreEffectCode: "stun", TriggerType: "on_turn_start", FactorType: "percent", Value: 20}, "supported"},
+		{CombatTestEffect{CoreEffectCode: "bleed", TriggerType: "on_every_other_turn", FactorType: "percent_of_max_hp", Value: 2}, "supported"},
+		{CombatTestEffect{CoreEffectCode: "modify_armor", TriggerType: "on_hit_taken", FactorType: "percent", Value: 10, Duration: &dur}, "supported"},
+		{CombatTestEffect{CoreEffectCode: "stun", TriggerType: "passive", FactorType: "percent", Value: 20}, "unsupported"},
+		{CombatTestEffect{CoreEffectCode: "stun", TriggerType: "on_crit", FactorType: "percent", Value: 20, Duration: &dur}, "supported"},
+		{CombatTestEffect{CoreEffectCode: "stun_resist", TriggerType: "passive", FactorType: "percent", Value: 20}, "supported"},
+		{CombatTestEffect{CoreEffectCode: "teleport", TriggerType: "on_hit", FactorType: "percent", Value: 20}, "unsupported"},
+		{CombatTestEffect{CoreEffectCode: "", TriggerType: "on_hit", FactorType: "percent", Value: 20}, "unsupported"},
+		{CombatTestEffect{CoreEffectCode: "damage", TriggerType: "on_hit", FactorType: "percent", Value: 20, Duration: &dur}, "partial"},
+		{CombatTestEffect{CoreEffectCode: "heal", TriggerType: "on_turn_end", FactorType: "percent_of_damage_taken", Value: 20}, "partial"},
+		{CombatTestEffect{CoreEffectCode: "dodge", TriggerType: "passive", FactorType: "percent", Value: 5, ConditionType: strPtr("hp_below_percent"), ConditionValue: intPtr(50)}, "partial"},
+		{CombatTestEffect{CoreEffectCode: "damage", TriggerType: "on_hit", FactorType: "percent", Value: 5, ConditionType: strPtr("target_stunned")}, "partial"},
+		{CombatTestEffect{CoreEffectCode: "poison", TriggerType: "on_hit", FactorType: "percent", Value: 5, Duration: &dur, Stacking: "refresh", Decay: 1}, "supported"},
+		{CombatTestEffect{CoreEffectCode: "burn", TriggerType: "on_hit", FactorType: "percent", Value: 5, Stacking: "max_stacks"}, "partial"},
+		{CombatTestEffect{CoreEffectCode: "damage", TriggerType: "on_hit", FactorType: "percent", Value: 5, Decay: 1}, "partial"},
+	}
+	for _, tc := range cases {
+		status, notes := checkEffectSupport(tc.eff)
+		if status != tc.status {
+			t.Errorf("%s/%s: got %s %v, want %s", tc.eff.CoreEffectCode, tc.eff.TriggerType, status, notes, tc.status)
+		}
+		if status != "supported" && len(notes) == 0 {
+			t.Errorf("%s/%s: %s without a note", tc.eff.CoreEffectCode, tc.eff.TriggerType, status)
+		}
+	}
+}
+
+// ── Test 125: Simulation aggregates seeded fights ──────────────────────
+
+func TestSimulateCombat(t *testing.T) {
+	c1 := baseCombatant(1, "Left", nil)
+	c2 := baseCombatant(2, "Right", nil)
+
+	sim := simulateCombat(c1, c2, 400, CombatOptions{Seed: 99, Rules: defaultCombatRules()})
+	if sim.Combatant1.Wins+sim.Combatant2.Wins != 400 {
+		t.Fatalf("Wins should add up to fights: %d + %d", sim.Combatant1.Wins, sim.Combatant2.Wins)
+	}
+	lengths := 0
+	for _, n := range sim.TurnCounts {
+		lengths += n
+	}
+	if lengths != 400 {
+		t.Errorf("Turn histogram covers %d fights, want 400", lengths)
+	}
+	// Mirror match with alternating first strike → the 95% interval should contain 50%
+	if sim.Combatant1.WinRateLow > 0.5 || sim.Combatant1.WinRateHigh < 0.5 {
+		t.Errorf("Mirror match win rate %.3f [%.3f, %.3f] excludes 0.5",
+			sim.Combatant1.WinRate, sim.Combatant1.WinRateLow, sim.Combatant1.WinRateHigh)
+	}
+	if sim.Combatant1.Stats.DamageDealt != sim.Combatant2.Stats.DamageTaken {
+		t.Errorf("Aggregated damage mismatch: dealt %d vs taken %d",
+			sim.Combatant1.Stats.DamageDealt, sim.Combatant2.Stats.DamageTaken)
+	}
+	if sim.AvgTurns <= 0 || sim.TimeoutRate < 0 || sim.TimeoutRate > 1 {
+		t.Errorf("Bad averages: turns=%.2f timeoutRate=%.2f", sim.AvgTurns, sim.TimeoutRate)
+	}
+
+	again := simulateCombat(c1, c2, 400, CombatOptions{Seed: 99, Rules: defaultCombatRules()})
+	if again.Combatant1.Wins != sim.Combatant1.Wins || again.AvgTurns != sim.AvgTurns {
+		t.Error("Same seed should reproduce the same simulation")
+	}
+
+	// Two tanks that can't kill each other in 30 turns always time out
+	tank1 := baseCombatant(1, "Tank1", nil)
+	tank1.Stamina = 500
+	tank2 := baseCombatant(2, "Tank2", nil)
+	tank2.Stamina = 500
+	stalled := simulateCombat(tank1, tank2, 20, CombatOptions{Seed: 1, Rules: defaultCombatRules()})
+	if stalled.TimeoutRate != 1 || stalled.TurnCounts[30] != 20 {
+		t.Errorf("Expected every tank fight to time out at turn 30, got rate=%.2f counts=%v", stalled.TimeoutRate, stalled.TurnCounts)
+	}
+
+	fmt.Printf("  Simulation: %.1f%% [%.1f, %.1f], avg %.1f turns\n",
+		sim.Combatant1.WinRate*100, sim.Combatant1.WinRateLow*100, sim.Combatant1.WinRateHigh*100, sim.AvgTurns)
+}
+
+// ── Test 126: Rules profiles drive the engine constants ──────────────────────
+
+func TestCombatRulesProfile(t *testing.T) {
+	rules := defaultCombatRules()
+	rules.MaxTurns = 5
+	rules.HPPerStamina = 100
+	rules.CritMultiplier = 300
+
+	c1 := baseCombatant(1, "A", nil)
+	c2 := baseCombatant(2, "B", nil)
+	result := executeCombatWith(c1, c2, CombatOptions{Seed: 3, Rules: rules})
+
+	if result.Header.Combatant1.MaxHP != 1000 {
+		t.Errorf("MaxHP with hpPerStamina=100: got %d, want 1000", result.Header.Combatant1.MaxHP)
+	}
+	if !result.Header.TimedOut || result.Header.Turns != 5 {
+		t.Errorf("Expected a timeout after 5 turns, got turns=%d timedOut=%v", result.Header.Turns, result.Header.TimedOut)
+	}
+	for _, e := range result.Log {
+		if e.Turn > 5 {
+			t.Fatalf("Log entry past maxTurns: %+v", e)
+		}
+		// baseCombatant hits for a flat 20 with no armor
+		if e.Action == "crit" && e.Factor != 60 {
+			t.Errorf("Crit with critMultiplier=300: got %d, want 60", e.Factor)
+		}
+	}
+
+	if got := rules.mitigate(100, 100); got != 50 {
+		t.Errorf("mitigate(100, 100): got %d, want 50", got)
+	}
+	if got := defaultCombatRules().chance(10, 10, 0); got != 10 {
+		t.Errorf("Equal-stat chance: got %d, want 10", got)
+	}
+	if got := defaultCombatRules().chance(1000, 1, 0); got != 50 {
+		t.Errorf("Chance should clamp at 50, got %d", got)
+	}
+
+	// Stored profiles missing newer keys fall back to the defaults
+	parsed, err := parseCombatRules([]byte(`{"maxTurns":12}`))
+	if err != nil {
+		t.Fatalf("parseCombatRules: %v", err)
+	}
+	want := defaultCombatRules()
+	want.MaxTurns = 12
+	if parsed != want {
+		t.Errorf("parseCombatRules: got %+v, want %+v", parsed, want)
+	}
+	if _, err := parseCombatRules([]byte(`{"chanceMin":60,"chanceMax":40}`)); err == nil {
+		t.Error("Expected an error for chanceMin > chanceMax")
+	}
+	if _, err := parseCombatRules([]byte(`{"maxTurns":0}`)); err == nil {
+		t.Error("Expected an error for maxTurns = 0")
+	}
+}
+
+// ── Test 127: Initiative modes ──────────────────────
+
+// actingOrder returns, per turn, the character IDs from the initiative entries
+func actingOrder(t *testing.T, log []CombatLogEntry) map[int][]int {
+	order := map[int][]int{}
+	for _, e := range log {
+		if e.Action == "initiative" {
+			order[e.Turn] = append(order[e.Turn], e.CharacterID)
+			if e.Factor != len(order[e.Turn]) {
+				t.Errorf("turn %d: initiative position %d out of sequence", e.Turn, e.Factor)
+			}
+		}
+	}
+	return order
+}
+
+func TestInitiativeModes(t *testing.T) {
+	tank := func(id int, name string) *CombatCharacter {
+		c := baseCombatant(id, name, nil)
+		c.Stamina = 200
+		return c
+	}
+
+	// fixed: no initiative entries, legacy behaviour
+	fixed := executeCombatWith(tank(1, "A"), tank(2, "B"), CombatOptions{Seed: 5, Rules: defaultCombatRules()})
+	if n := len(actingOrder(t, fixed.Log)); n != 0 {
+		t.Errorf("fixed mode should not log initiative, got %d turns", n)
+	}
+
+	// agility: the faster side acts first every turn even when passed second
+	rules := defaultCombatRules()
+	rules.Initiative = initiativeAgility
+	slow, fast := tank(1, "Slow"), tank(2, "Fast")
+	fast.Agility = 15
+	result := executeCombatWith(slow, fast, CombatOptions{Seed: 5, Rules: rules})
+	order := actingOrder(t, result.Log)
+	if len(order) == 0 {
+		t.Fatal("agility mode logged no initiative")
+	}
+	for turn, ids := range order {
+		if ids[0] != 2 {
+			t.Errorf("agility turn %d: Fast should act first, got %v", turn, ids)
+		}
+	}
+
+	// agility tie → luck decides
+	unlucky, lucky := tank(1, "Unlucky"), tank(2, "Lucky")
+	unlucky.Luck = 5
+	result = executeCombatWith(unlucky, lucky, CombatOptions{Seed: 5, Rules: rules})
+	if ids := actingOrder(t, result.Log)[1]; len(ids) == 0 || ids[0] != 2 {
+		t.Errorf("agility tie: higher luck should act first, got %v", ids)
+	}
+
+	// speed: twice the agility → two actions per turn
+	rules.Initiative = initiativeSpeed
+	slow, fast = tank(1, "Slow"), tank(2, "Fast")
+	fast.Agility = 20
+	result = executeCombatWith(slow, fast, CombatOptions{Seed: 5, Rules: rules})
+	order = actingOrder(t, result.Log)
+	for turn := 1; turn <= 3; turn++ {
+		fastActs, slowActs := 0, 0
+		for _, id := range order[turn] {
+			if id == 2 {
+				fastActs++
+			} else {
+				slowActs++
+			}
+		}
+		if fastActs != 2 || slowActs != 1 {
+			t.Errorf("speed turn %d: want Fast×2 Slow×1, got %v", turn, order[turn])
+		}
+	}
+	if result.Header.Turns > rules.MaxTurns {
+		t.Errorf("speed mode ran past maxTurns: %d", result.Header.Turns)
+	}
+
+	if _, err := parseCombatRules([]byte(`{"initiative":"random"}`)); err == nil {
+		t.Error("Expected an error for an unknown initiative mode")
+	}
+}
+
+// ── Test 128: Shield absorbs damage before HP ──────────────────────
+
+func TestShieldAbsorb(t *testing.T) {
+	// Flat 30-point shield from on_start soaks up the first 30 damage
+	c1 := baseCombatant(1, "Warded", []CombatTestEffect{
+		{EffectID: 950, CoreEffectCode: "shield", TriggerType: "on_start", FactorType: "flat", TargetSelf: true, Value: 30},
+	})
+	c1.Stamina = 50
+	c2 := baseCombatant(2, "Hitter", nil)
+	c2.Stamina = 50
+	result := executeCombatSeeded(c1, c2, 3)
+
+	shields, breaks, hpDamage := 0, 0, 0
+	for _, e := range result.Log {
+		switch e.Action {
+		case "shield":
+			shields++
+			if e.CharacterID != 1 || e.Factor != 30 {
+				t.Errorf("shield entry should put 30 points on Warded, got %+v", e)
+			}
+		case "shield_break":
+			breaks++
+		case "attack", "crit", "counterattack":
+			if e.CharacterID == 2 {
+				hpDamage += e.Factor - e.Absorbed
+			}
+		}
+	}
+	if shields != 1 || breaks != 1 {
+		t.Errorf("Expected one shield and one shield_break, got %d and %d", shields, breaks)
+	}
+	s1, s2 := result.Stats.Combatant1, result.Stats.Combatant2
+	if s1.ShieldAbsorbed != 30 {
+		t.Errorf("Expected 30 absorbed, got %d", s1.ShieldAbsorbed)
+	}
+	if s1.DamageTaken != hpDamage || s2.DamageDealt != hpDamage {
+		t.Errorf("Absorbed damage must not count: taken %d, dealt %d, log says %d", s1.DamageTaken, s2.DamageDealt, hpDamage)
+	}
+
+	// Percent of max HP is sized from the wearer
+	ward := CombatTestEffect{EffectID: 951, CoreEffectCode: "shield", TriggerType: "passive", FactorType: "percent_of_max_hp", Value: 25}
+	c := newCombatant(baseCombatant(1, "Passive", []CombatTestEffect{ward}), defaultCombatRules())
+	(&combatEngine{}).applyPassives(c)
+	if len(c.shields) != 1 || c.shields[0].points != 25 || c.shields[0].remaining != 0 {
+		t.Errorf("Expected a permanent 25-point layer, got %+v", c.shields)
+	}
+
+	// Percent of damage taken, timed: each hit refills a layer that expires
+	dur := 1
+	c1 = baseCombatant(1, "Reactive", []CombatTestEffect{
+		{EffectID: 952, CoreEffectCode: "shield", TriggerType: "on_hit_taken", FactorType: "percent_of_damage_taken", TargetSelf: true, Value: 50, Duration: &dur},
+	})
+	c1.Stamina = 50
+	c1.Strength = 0 // Reactive barely scratches Hitter, so its shields outlive their duration
+	c1.MinDamage, c1.MaxDamage = 1, 1
+	result = executeCombatSeeded(c1, baseCombatant(2, "Hitter", nil), 3)
+	granted, expired := 0, 0
+	for _, e := range result.Log {
+		if e.Action == "shield" && e.EffectID != nil && *e.EffectID == 952 {
+			granted++
+			if e.Duration == nil || *e.Duration != 1 {
+				t.Errorf("timed shield should log its duration, got %+v", e)
+			}
+		}
+		if e.Action == "buff_expire" && e.BuffType == "shield" {
+			expired++
+		}
+	}
+	if granted == 0 || expired == 0 {
+		t.Errorf("Expected timed shields to be granted and expire, got %d granted, %d expired", granted, expired)
+	}
+	if result.Stats.Combatant1.ShieldAbsorbed == 0 {
+		t.Error("Expected the reactive shield to absorb something")
+	}
+}
+
+// ── Test 129: DoT durations, stacking and decay ──────────────────────
+
+func TestDamageOverTime(t *testing.T) {
+	newEngine := func() *combatEngine {
+		e := &combatEngine{rules: defaultCombatRules(), rng: rand.New(rand.NewSource(1))}
+		e.watch(&combatLog{})
+		return e
+	}
+	victim := func() *combatant {
+		return newCombatant(baseCombatant(2, "Victim", nil), defaultCombatRules())
+	}
+	ticks := func(e *combatEngine, kind string) []int {
+		var out []int
+		for _, l := range logOf(e) {
+			if l.Action == kind && l.TriggerType == "" {
+				out = append(out, l.Factor)
+			}
+		}
+		return out
+	}
+
+	// Decay wears a stack down: 10, 6, 2, then it is gone
+	e, c := newEngine(), victim()
+	c.addDot(dotStack{kind: "poison", effectID: 1, damage: 10, decay: 4, ignoreArmor: true}, "", 0)
+	for i := 0; i < 4; i++ {
+		e.tickDots(c)
+	}
+	if got := ticks(e, "poison"); !reflect.DeepEqual(got, []int{10, 6, 2}) {
+		t.Errorf("decay: got ticks %v, want [10 6 2]", got)
+	}
+	if c.hp != 82 || len(c.dots) != 0 {
+		t.Errorf("decay: expected 82 HP and no stacks left, got %d HP and %d stacks", c.hp, len(c.dots))
+	}
+
+	// Duration counts ticks; stacks of one type tick together
+	e, c = newEngine(), victim()
+	c.addDot(dotStack{kind: "bleed", effectID: 1, damage: 3, remaining: 2, ignoreArmor: true}, "", 0)
+	c.addDot(dotStack{kind: "bleed", effectID: 1, damage: 5, remaining: 1, ignoreArmor: true}, "", 0)
+	for i := 0; i < 3; i++ {
+		e.tickDots(c)
+	}
+	if got := ticks(e, "bleed"); !reflect.DeepEqual(got, []int{8, 3}) {
+		t.Errorf("duration: got ticks %v, want [8 3]", got)
+	}
+	expired := 0
+	for _, l := range logOf(e) {
+		if l.Action == "buff_expire" && l.BuffType == "bleed" {
+			expired++
+		}
+	}
+	if expired != 2 {
+		t.Errorf("duration: expected 2 expiry entries, got %d", expired)
+	}
+
+	// Stacking policies
+	c = victim()
+	for i := 1; i <= 3; i++ {
+		c.addDot(dotStack{kind: "poison", effectID: 7, damage: i}, stackingRefresh, 0)
+	}
+	if len(c.dots) != 1 || c.dots[0].damage != 3 {
+		t.Errorf("refresh: expected one stack of 3, got %+v", c.dots)
+	}
+	c = victim()
+	for i := 1; i <= 3; i++ {
+		c.addDot(dotStack{kind: "poison", effectID: 7, damage: i}, stackingMax, 2)
+	}
+	c.addDot(dotStack{kind: "poison", effectID: 8, damage: 9}, stackingMax, 2)
+	if len(c.dots) != 3 || c.dots[0].damage != 2 || c.dots[1].damage != 3 {
+		t.Errorf("max_stacks: expected the oldest stack of effect 7 replaced, got %+v", c.dots)
+	}
+
+	// Burn goes through armor unless ignoreArmor is set
+	e, c = newEngine(), victim()
+	c.Armor = 100
+	c.addDot(dotStack{kind: "burn", effectID: 1, damage: 20}, "", 0)
+	e.tickDots(c)
+	if got := ticks(e, "burn"); len(got) != 1 || got[0] != 10 {
+		t.Errorf("burn: expected 20 halved by armor, got %v", got)
+	}
+
+	// Effect fields reach the stacks through a real fight
+	ignore := false
+	dur := 3
+	c1 := baseCombatant(1, "Caster", []CombatTestEffect{
+		{EffectID: 960, CoreEffectCode: "burn", TriggerType: "on_hit", FactorType: "percent", Value: 4, Duration: &dur, Stacking: stackingMax, MaxStacks: 2, IgnoreArmor: &ignore},
+		{EffectID: 961, CoreEffectCode: "poison", TriggerType: "on_start", FactorType: "percent", Value: 6, Decay: 2},
+	})
+	c1.Stamina = 50
+	c2 := baseCombatant(2, "Target", nil)
+	c2.Stamina = 50
+	result := executeCombatSeeded(c1, c2, 11)
+	s := result.Stats.Combatant1
+	if s.BurnApplied == 0 || s.PoisonApplied != 6 {
+		t.Errorf("Expected burn applied and 6 poison, got burn %d poison %d", s.BurnApplied, s.PoisonApplied)
+	}
+	var poison []int
+	for _, l := range result.Log {
+		if l.Action == "poison" && l.TriggerType == "" {
+			poison = append(poison, l.Factor)
+		}
+		if l.Action == "burn" && l.TriggerType == "" && l.Factor > 8 {
+			t.Errorf("burn ticked for %d with max 2 stacks of 4", l.Factor)
+		}
+	}
+	if !reflect.DeepEqual(poison, []int{6, 4, 2}) {
+		t.Errorf("Expected decaying poison ticks [6 4 2], got %v", poison)
+	}
+}
+
+// ── Test 130: Party combat, targeting and AoE ──────────────────────
+
+func TestPartyCombat(t *testing.T) {
+	member := func(id int, name string, stamina int, effects []CombatTestEffect) *CombatCharacter {
+		c := baseCombatant(id, name, effects)
+		c.Stamina = stamina
+		return c
+	}
+
+	// 2v1: the pair wins, every attack names its target, and the team is wiped only when both are down
+	duo := CombatTeam{Members: []*CombatCharacter{member(1, "Hero", 20, nil), member(2, "Companion", 20, nil)}}
+	boss := CombatTeam{Members: []*CombatCharacter{member(3, "Boss", 25, nil)}}
+	wins := 0
+	for seed := int64(1); seed <= 30; seed++ {
+		result := executePartyCombat(duo, boss, CombatOptions{Seed: seed, Rules: defaultCombatRules()})
+		if result.Header.WinnerTeam == 1 {
+			wins++
+		} else {
+			for _, s := range result.Header.Team1 {
+				if s.HPEnd > 0 && !result.Header.TimedOut {
+					t.Errorf("seed %d: team 1 lost with %s still standing", seed, s.Name)
+				}
+			}
+		}
+		for _, e := range result.Log {
+			if e.Action != "attack" && e.Action != "crit" {
+				continue
+			}
+			if e.TargetID == 0 || e.TargetID == e.CharacterID {
+				t.Fatalf("seed %d: attack without a proper target: %+v", seed, e)
+			}
+			if (e.CharacterID == 3) == (e.TargetID == 3) {
+				t.Fatalf("seed %d: attack within a team: %+v", seed, e)
+			}
+		}
+	}
+	if wins < 20 {
+		t.Errorf("Expected the pair to win most fights against one boss, won %d/30", wins)
+	}
+
+	// lowest_hp focuses the weakest member; highest_threat the hardest hitter
+	// (checked from turn 2, once both have attacked)
+	targets := func(targeting string) map[int]int {
+		weak := member(1, "Weak", 50, nil)
+		weak.DepletedHealth = 400
+		hitter := member(2, "Hitter", 50, nil)
+		hitter.Strength = 60
+		party := CombatTeam{Members: []*CombatCharacter{weak, hitter}}
+		enemy := CombatTeam{Members: []*CombatCharacter{member(3, "Enemy", 100, nil)}, Targeting: targeting}
+		result := executePartyCombat(party, enemy, CombatOptions{Seed: 4, Rules: defaultCombatRules()})
+		hits := map[int]int{}
+		for _, e := range result.Log {
+			if e.CharacterID == 3 && e.Turn >= 2 && e.Turn <= 3 && (e.Action == "attack" || e.Action == "crit" || e.Action == "dodge") {
+				hits[e.TargetID]++
+			}
+		}
+		return hits
+	}
+	if hits := targets(targetLowestHP); hits[1] == 0 || hits[2] != 0 {
+		t.Errorf("lowest_hp: expected only Weak targeted early, got %v", hits)
+	}
+	if hits := targets(targetHighestThreat); hits[2] == 0 || hits[1] != 0 {
+		t.Errorf("highest_threat: expected only Hitter targeted early, got %v", hits)
+	}
+
+	// AoE lands on every living enemy; AoE + targetSelf on every ally
+	caster := member(1, "Caster", 50, []CombatTestEffect{
+		{EffectID: 970, CoreEffectCode: "damage", TriggerType: "on_start", FactorType: "flat", Value: 7, AoE: true},
+		{EffectID: 971, CoreEffectCode: "shield", TriggerType: "on_start", FactorType: "flat", Value: 5, AoE: true, TargetSelf: true},
+	})
+	allies := CombatTeam{Members: []*CombatCharacter{caster, member(2, "Ally", 50, nil)}}
+	pack := CombatTeam{Members: []*CombatCharacter{member(3, "Wolf", 50, nil), member(4, "Wolf", 50, nil), member(5, "Wolf", 50, nil)}}
+	result := executePartyCombat(allies, pack, CombatOptions{Seed: 1, Rules: defaultCombatRules()})
+	hit, shielded := map[int]bool{}, map[int]bool{}
+	for _, e := range result.Log {
+		if e.Turn != 0 || e.EffectID == nil {
+			continue
+		}
+		switch *e.EffectID {
+		case 970:
+			hit[e.TargetID] = true
+		case 971:
+			shielded[e.TargetID] = true
+		}
+	}
+	if len(hit) != 3 || !hit[3] || !hit[4] || !hit[5] {
+		t.Errorf("AoE damage should hit all three wolves, hit %v", hit)
+	}
+	if len(shielded) != 2 || !shielded[1] || !shielded[2] {
+		t.Errorf("AoE self shield should cover both allies, covered %v", shielded)
+	}
+	for i, s := range result.Stats.Team2 {
+		if s.DamageTaken < 7 {
+			t.Errorf("wolf %d took %d damage, expected at least the AoE 7", i, s.DamageTaken)
+		}
+	}
+
+	// Timeouts compare living HP: an overkilled member doesn't drag its team below 0
+	fallen, standing := newCombatant(member(1, "Fallen", 10, nil), defaultCombatRules()), newCombatant(member(2, "Standing", 10, nil), defaultCombatRules())
+	fallen.hp, standing.hp = -80, 30
+	if hp := teamHP([]*combatant{fallen, standing}); hp != 30 {
+		t.Errorf("Expected the dead member to count as 0 HP, got team HP %d", hp)
+	}
+}
+
+// ── Test 131: Structured effect conditions ──────────────────────
+
+func TestStructuredConditions(t *testing.T) {
+	rules := defaultCombatRules()
+	owner := newCombatant(baseCombatant(1, "Owner", nil), rules)
+	foe := newCombatant(baseCombatant(2, "Foe", nil), rules)
+	foe.hp = 20
+	cc := &conditionContext{owner: owner, opponent: foe, target: owner, turn: 3}
+
+	cases := []struct {
+		name string
+		cond EffectCondition
+		want bool
+	}{
+		{"opponent below 30%", EffectCondition{Type: "hp_below_percent", Subject: "opponent", Value: 30}, true},
+		{"target (self here) below 30%", EffectCondition{Type: "hp_below_percent", Value: 30}, false},
+		{"self above 90%", EffectCondition{Type: "hp_above_percent", Subject: "self", Value: 90}, true},
+		{"turn >= 3", EffectCondition{Type: "turn_at_least", Value: 3}, true},
+		{"turn >= 4", EffectCondition{Type: "turn_at_least", Value: 4}, false},
+		{"opponent bleeding", EffectCondition{Type: "bleeding", Subject: "opponent"}, false},
+		{"first attack", EffectCondition{Type: "first_attack"}, true},
+		{"after dodged", EffectCondition{Type: "after_dodged"}, false},
+		{"and", EffectCondition{Type: "and", Conditions: []EffectCondition{
+			{Type: "turn_at_least", Value: 2}, {Type: "hp_below_percent", Subject: "opponent", Value: 30},
+		}}, true},
+		{"and short-circuits false", EffectCondition{Type: "and", Conditions: []EffectCondition{
+			{Type: "turn_at_least", Value: 2}, {Type: "stunned", Subject: "opponent"},
+		}}, false},
+		{"or", EffectCondition{Type: "or", Conditions: []EffectCondition{
+			{Type: "stunned", Subject: "opponent"}, {Type: "turn_at_least", Value: 1},
+		}}, true},
+	}
+	for _, tc := range cases {
+		if got := tc.cond.eval(cc); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	foe.addDot(dotStack{kind: "bleed", damage: 1}, "", 0)
+	foe.stunTurns = 1
+	owner.attacksStarted = 2
+	owner.lastAttackDodged = true
+	for _, c := range []EffectCondition{
+		{Type: "bleeding", Subject: "opponent"},
+		{Type: "stunned", Subject: "opponent"},
+		{Type: "after_dodged"},
+	} {
+		if !c.eval(cc) {
+			t.Errorf("%s on opponent should now hold", c.Type)
+		}
+	}
+	if (&EffectCondition{Type: "first_attack"}).eval(cc) {
+		t.Error("first_attack should not hold once the second attack started")
+	}
+
+	// In a fight: a finisher only fires while the opponent is low, a riposte only after a dodge
+	c1 := baseCombatant(1, "Finisher", []CombatTestEffect{
+		{EffectID: 980, CoreEffectCode: "damage", TriggerType: "on_hit", FactorType: "flat", Value: 5,
+			Condition: &EffectCondition{Type: "hp_below_percent", Subject: "opponent", Value: 50}},
+		{EffectID: 981, CoreEffectCode: "damage", TriggerType: "on_turn_end", FactorType: "flat", Value: 1,
+			Condition: &EffectCondition{Type: "after_dodged"}},
+		{EffectID: 982, CoreEffectCode: "heal", TriggerType: "on_hit", FactorType: "flat", Value: 1, TargetSelf: true,
+			Condition: &EffectCondition{Type: "first_attack"}},
+	})
+	c1.Stamina = 30
+	c2 := baseCombatant(2, "Dodger", nil)
+	c2.Stamina = 30
+	c2.Agility = 25
+	result := executeCombatSeeded(c1, c2, 9)
+	firstHeals, ripostes := 0, 0
+	lastDodged := false
+	for _, e := range result.Log {
+		if e.CharacterID == 1 {
+			switch e.Action {
+			case "dodge":
+				lastDodged = true
+			case "attack", "crit":
+				lastDodged = false
+			}
+		}
+		if e.EffectID == nil {
+			continue
+		}
+		switch *e.EffectID {
+		case 981:
+			ripostes++
+			if !lastDodged {
+				t.Errorf("turn %d: after_dodged fired after a landed attack", e.Turn)
+			}
+		case 982:
+			firstHeals++
+		}
+	}
+	if firstHeals > 1 {
+		t.Errorf("first_attack heal fired %d times", firstHeals)
+	}
+	if ripostes == 0 {
+		t.Error("after_dodged never fired against a high-agility dodger")
+	}
+
+	// Support report walks the tree
+	eff := CombatTestEffect{CoreEffectCode: "damage", TriggerType: "on_hit", FactorType: "flat", Value: 5,
+		Condition: &EffectCondition{Type: "or", Conditions: []EffectCondition{{Type: "moon_phase"}, {Type: "turn_at_least", Subject: "self", Value: 2}}}}
+	status, notes := checkEffectSupport(eff)
+	if status != "partial" || len(notes) != 2 {
+		t.Errorf("Expected 2 condition notes, got %s %v", status, notes)
+	}
+}
+
+// ── Test 132: Cooldowns, proc caps and proc chance ──────────────────────
+
+func TestEffectProcLimits(t *testing.T) {
+	procTurns := func(eff CombatTestEffect, seed int64) ([]int, *CombatResult) {
+		c1 := baseCombatant(1, "Procer", []CombatTestEffect{eff})
+		c1.Stamina = 50
+		c2 := baseCombatant(2, "Dummy", nil)
+		c2.Stamina = 50
+		result := executeCombatSeeded(c1, c2, seed)
+		var turns []int
+		for _, e := range result.Log {
+			if e.EffectID != nil && *e.EffectID == eff.EffectID && e.Action == "damage" {
+				turns = append(turns, e.Turn)
+			}
+		}
+		return turns, result
+	}
+
+	// Cooldown 3 on every turn start: turns 1, 4, 7, ...
+	turns, result := procTurns(CombatTestEffect{EffectID: 990, CoreEffectCode: "damage", TriggerType: "on_turn_start", FactorType: "flat", Value: 1, Cooldown: 3}, 1)
+	if len(turns) < 3 {
+		t.Fatalf("cooldown 3: expected several procs, got %v", turns)
+	}
+	for i, turn := range turns {
+		if turn != 1+3*i {
+			t.Fatalf("cooldown 3: procs on turns %v", turns)
+		}
+	}
+	if got := result.Stats.Combatant1.Procs[990]; got != len(turns) {
+		t.Errorf("Expected stats to count %d procs, got %d", len(turns), got)
+	}
+
+	// Max procs caps a fight
+	turns, result = procTurns(CombatTestEffect{EffectID: 991, CoreEffectCode: "damage", TriggerType: "on_turn_start", FactorType: "flat", Value: 1, MaxProcs: 2}, 1)
+	if len(turns) != 2 || result.Stats.Combatant1.Procs[991] != 2 {
+		t.Errorf("maxProcs 2: got procs on %v", turns)
+	}
+
+	// Cooldown 1 stops an on_hit stun from procing twice in one turn through double attack
+	stunLock := CombatTestEffect{EffectID: 992, CoreEffectCode: "stun", TriggerType: "on_hit", FactorType: "percent", Value: 100, Cooldown: 1}
+	c1 := baseCombatant(1, "Locker", []CombatTestEffect{stunLock,
+		{EffectID: 993, CoreEffectCode: "double_attack", TriggerType: "passive", FactorType: "percent", Value: 100},
+	})
+	result = executeCombatSeeded(c1, baseCombatant(2, "Victim", nil), 1)
+	perTurn := map[int]int{}
+	for _, e := range result.Log {
+		if e.Action == "stun" {
+			perTurn[e.Turn]++
+		}
+	}
+	for turn, n := range perTurn {
+		if n > 1 {
+			t.Errorf("turn %d: stun procced %d times despite cooldown", turn, n)
+		}
+	}
+
+	// Proc chance is rolled separately from value
+	procs := 0
+	for seed := int64(1); seed <= 20; seed++ {
+		turns, _ := procTurns(CombatTestEffect{EffectID: 994, CoreEffectCode: "damage", TriggerType: "on_turn_start", FactorType: "flat", Value: 1, ProcChance: intPtr(25)}, seed)
+		procs += len(turns)
+	}
+	// 20 fights of up to 30 turns at 25%: far from both 0 and every turn
+	if procs == 0 || procs > 20*30/2 {
+		t.Errorf("procChance 25: got %d procs over 20 fights", procs)
+	}
+
+	zero := 0
+	if status, _ := checkEffectSupport(CombatTestEffect{CoreEffectCode: "damage", TriggerType: "on_hit", FactorType: "flat", Value: 1, ProcChance: &zero}); status != "partial" {
+		t.Errorf("procChance 0 should be flagged, got %s", status)
+	}
+	if status, _ := checkEffectSupport(CombatTestEffect{CoreEffectCode: "dodge", TriggerType: "passive", FactorType: "percent", Value: 1, Cooldown: 2}); status != "partial" {
+		t.Errorf("cooldown on a passive should be flagged, got %s", status)
+	}
+}
+
+// ── Test 133: Healing pipeline ──────────────────────
+
+func TestHealingPipeline(t *testing.T) {
+	rules := defaultCombatRules()
+	e := &combatEngine{rules: rules}
+	e.watch(&combatLog{})
+	healer := newCombatant(baseCombatant(1, "Healer", nil), rules)
+	patient := newCombatant(baseCombatant(2, "Patient", nil), rules)
+
+	// +50% done, -20% received, 50% anti-heal: 40 → 60 → 48 → 24
+	healer.mods.HealDoneModifier = 50
+	patient.mods.HealModifier = -20
+	patient.mods.AntiHeal = 50
+	patient.hp = 50
+	healed, overheal := e.heal(healer, patient, 40, "heal", 0)
+	if healed != 24 || overheal != 0 || patient.hp != 74 {
+		t.Errorf("Expected 24 healed to 74 HP, got %d (overheal %d) to %d HP", healed, overheal, patient.hp)
+	}
+	if patient.stats.HealingBlocked != 24 || healer.stats.HealingDone != 24 {
+		t.Errorf("Expected 24 blocked and 24 done, got %d and %d", patient.stats.HealingBlocked, healer.stats.HealingDone)
+	}
+
+	// Overheal is logged and counted but never applied
+	healed, overheal = e.heal(healer, patient, 100, "heal", 0)
+	if patient.hp != patient.maxHP || overheal != healed-26 {
+		t.Errorf("Expected a heal to full with %d overheal, got %d HP and %d overheal", healed-26, patient.hp, overheal)
+	}
+	if healer.stats.HealingDone != 50 || healer.stats.Overheal != overheal {
+		t.Errorf("Expected 50 effective healing and %d overheal, got %d and %d", overheal, healer.stats.HealingDone, healer.stats.Overheal)
+	}
+
+	// Anti-heal is capped at 100%
+	patient.hp = 10
+	patient.mods.AntiHeal = 250
+	if healed, _ := e.heal(healer, patient, 40, "heal", 0); healed != 0 || patient.hp != 10 {
+		t.Errorf("Full anti-heal should block everything, healed %d", healed)
+	}
+
+	// A negative heal drains like damage, booked and able to kill
+	deaths := &countingObserver{damageTaken: map[int]int{}}
+	e.watch(deaths)
+	taken := patient.stats.DamageTaken
+	if healed, _ := e.heal(healer, patient, -15, "heal", 0); healed != -15 || patient.hp != -5 ||
+		patient.stats.DamageTaken != taken+15 || len(deaths.deaths) != 1 {
+		t.Errorf("Expected a 15 HP drain that kills, got %d healed to %d HP, %d taken, %d deaths",
+			healed, patient.hp, patient.stats.DamageTaken-taken, len(deaths.deaths))
+	}
+
+	// In a fight: an anti-heal debuff from on_start shrinks the enemy's heals
+	dur := 10
+	regen := CombatTestEffect{EffectID: 995, CoreEffectCode: "heal", TriggerType: "on_turn_start", FactorType: "flat", Value: 20, TargetSelf: true}
+	healing := func(debuff bool) (int, int) {
+		var effects []CombatTestEffect
+		if debuff {
+			effects = append(effects, CombatTestEffect{EffectID: 996, CoreEffectCode: "anti_heal", TriggerType: "on_start", FactorType: "percent", Value: 50, Duration: &dur})
+		}
+		c1 := baseCombatant(1, "Wounder", effects)
+		c1.Stamina = 30
+		c2 := baseCombatant(2, "Regen", []CombatTestEffect{regen})
+		c2.Stamina = 30
+		result := executeCombatSeeded(c1, c2, 2)
+		heals := 0
+		for _, l := range result.Log {
+			if l.Action == "heal" && l.Turn <= 10 && l.Factor != 20 && !debuff {
+				t.Errorf("undebuffed heal should be 20, got %d", l.Factor)
+			}
+			if l.Action == "heal" && l.Turn <= 10 {
+				heals += l.Factor
+			}
+		}
+		return heals, result.Stats.Combatant2.HealingBlocked
+	}
+	plain, _ := healing(false)
+	cursed, blocked := healing(true)
+	if cursed*2 != plain || blocked < plain/2 {
+		t.Errorf("Expected anti-heal to halve the first 10 turns of healing: %d vs %d, %d blocked", cursed, plain, blocked)
+	}
+
+	// Anti-heal on its own owner is a self-debuff worth flagging
+	for _, trigger := range []string{"passive", "on_hit"} {
+		if status, _ := checkEffectSupport(CombatTestEffect{CoreEffectCode: "anti_heal", TriggerType: trigger, Value: 50, TargetSelf: true}); status != "partial" {
+			t.Errorf("%s anti_heal with targetSelf: expected partial, got %s", trigger, status)
+		}
+	}
+	if status, notes := checkEffectSupport(CombatTestEffect{CoreEffectCode: "anti_heal", TriggerType: "passive", Value: 50}); status != "supported" {
+		t.Errorf("Passive anti_heal on the enemy: expected supported, got %s %v", status, notes)
+	}
+}
+
+// ── Test 134: Lifesteal, reflect and armor penetration ──────────────────────
+
+func TestLifestealReflectPenetration(t *testing.T) {
+	sum := func(result *CombatResult, action string, actor int) (total, entries int) {
+		for _, e := range result.Log {
+			if e.Action == action && e.CharacterID == actor {
+				total += e.Factor - e.Overheal
+				entries++
+			}
+		}
+		return
+	}
+
+	// Passive lifesteal 50%: every landed 20-damage hit heals 10
+	c1 := baseCombatant(1, "Vampire", []CombatTestEffect{
+		{EffectID: 1001, CoreEffectCode: "lifesteal", TriggerType: "passive", FactorType: "percent", Value: 50},
+	})
+	c1.Stamina = 30
+	c2 := baseCombatant(2, "Dummy", nil)
+	c2.Stamina = 30
+	result := executeCombatSeeded(c1, c2, 5)
+	healed, n := sum(result, "lifesteal", 1)
+	if n == 0 {
+		t.Fatal("lifesteal never logged")
+	}
+	if s := result.Stats.Combatant1; s.LifestealHealing != healed || s.HealingDone != healed {
+		t.Errorf("Expected %d lifesteal healing in stats, got %d (healingDone %d)", healed, s.LifestealHealing, s.HealingDone)
+	}
+	for _, e := range result.Log {
+		if e.Action == "lifesteal" && e.TargetID != 1 {
+			t.Errorf("lifesteal should land on its owner, got %+v", e)
+		}
+	}
+
+	// Reflect 50% on the defender: half of each hit goes back, credited to the defender
+	c1 = baseCombatant(1, "Attacker", nil)
+	c1.Stamina = 30
+	c2 = baseCombatant(2, "Thorns", []CombatTestEffect{
+		{EffectID: 1002, CoreEffectCode: "reflect_damage", TriggerType: "passive", FactorType: "percent", Value: 50},
+	})
+	c2.Stamina = 30
+	result = executeCombatSeeded(c1, c2, 5)
+	reflected, n := sum(result, "reflect_damage", 2)
+	attacks, _ := sum(result, "attack", 1)
+	crits, _ := sum(result, "crit", 1)
+	if n == 0 || reflected < (attacks+crits)/2-n || reflected > (attacks+crits)/2 {
+		t.Errorf("Expected about half of %d reflected, got %d over %d entries", attacks+crits, reflected, n)
+	}
+	if result.Stats.Combatant2.DamageReflected != reflected {
+		t.Errorf("Expected %d reflected in stats, got %d", reflected, result.Stats.Combatant2.DamageReflected)
+	}
+
+	// Armor penetration: 100 armor halves 20 damage; 100% penetration ignores it
+	c1 = baseCombatant(1, "Piercer", []CombatTestEffect{
+		{EffectID: 1003, CoreEffectCode: "armor_penetration", TriggerType: "passive", FactorType: "percent", Value: 100},
+	})
+	c1.Stamina = 30
+	c2 = baseCombatant(2, "Plated", nil)
+	c2.Stamina = 30
+	c2.Armor = 100
+	result = executeCombatSeeded(c1, c2, 5)
+	for i, e := range result.Log {
+		if e.CharacterID == 1 && e.Action == "attack" && e.Factor != 20 {
+			t.Errorf("penetrating attack should ignore armor, got %d", e.Factor)
+		}
+		if e.Action == "armor_penetration" && i+1 < len(result.Log) && result.Log[i+1].Action == "attack" && e.Factor != 10 {
+			t.Errorf("armor_penetration should log the 10 damage it added, got %+v", e)
+		}
+	}
+	if _, n := sum(result, "armor_penetration", 1); n == 0 {
+		t.Error("armor_penetration never logged")
+	}
+
+	// Triggered form: an on_crit lifesteal buff with a duration
+	dur := 2
+	status, notes := checkEffectSupport(CombatTestEffect{CoreEffectCode: "lifesteal", TriggerType: "on_crit", FactorType: "percent", Value: 30, Duration: &dur, TargetSelf: true})
+	if status != "supported" {
+		t.Errorf("triggered lifesteal should be supported, got %s %v", status, notes)
+	}
+}
+
+// ── Test 135: Stun duration, resist, diminishing returns and immunity ──────────────────────
+
+func TestStunRules(t *testing.T) {
+	stunnedTurns := func(result *CombatResult, id int) []int {
+		var turns []int
+		for _, e := range result.Log {
+			if e.Action == "stunned" && e.CharacterID == id {
+				turns = append(turns, e.Turn)
+			}
+		}
+		return turns
+	}
+	dur := 3
+
+	// Multi-turn: an on_start stun for 3 turns costs the target its first three turns
+	c1 := baseCombatant(1, "Stunner", []CombatTestEffect{
+		{EffectID: 1101, CoreEffectCode: "stun", TriggerType: "on_start", FactorType: "percent", Value: 100, Duration: &dur},
+	})
+	c1.Stamina = 30
+	c2 := baseCombatant(2, "Victim", nil)
+	c2.Stamina = 30
+	result := executeCombatSeeded(c1, c2, 3)
+	turns := stunnedTurns(result, 2)
+	if len(turns) != 3 || turns[1] != turns[0]+1 || turns[2] != turns[1]+1 {
+		t.Fatalf("Expected three consecutive stunned turns, got %v", turns)
+	}
+	for _, e := range result.Log {
+		if e.CharacterID == 2 && (e.Action == "attack" || e.Action == "crit" || e.Action == "dodge") && e.Turn < turns[2] {
+			t.Errorf("Victim acted on turn %d while stunned", e.Turn)
+		}
+	}
+	if result.Stats.Combatant2.TimesStunned != 3 || result.Stats.Combatant1.StunApplied != 1 {
+		t.Errorf("Expected 1 stun applied and 3 turns lost, got %+v", result.Stats)
+	}
+
+	// Immunity: two own turns without stuns between every stunned turn
+	rules := defaultCombatRules()
+	rules.StunImmunity = 2
+	c1.Effects = []CombatTestEffect{
+		{EffectID: 1102, CoreEffectCode: "stun", TriggerType: "on_hit", FactorType: "percent", Value: 100},
+	}
+	result = executeCombatWith(c1, c2, CombatOptions{Seed: 3, Rules: rules})
+	turns = stunnedTurns(result, 2)
+	if len(turns) < 2 {
+		t.Fatalf("Expected repeated stuns, got %v", turns)
+	}
+	for i := 1; i < len(turns); i++ {
+		if turns[i]-turns[i-1] < 3 {
+			t.Errorf("Stunned on turns %d and %d inside the immunity window", turns[i-1], turns[i])
+		}
+	}
+	if result.Stats.Combatant2.StunsImmune == 0 {
+		t.Error("Expected stuns blocked by immunity to be counted")
+	}
+	logged := map[string]bool{}
+	for _, e := range result.Log {
+		logged[e.Action+e.BuffType] = true
+	}
+	for _, action := range []string{"stun_immune", "stun_immunity", "buff_expirestun_immunity"} {
+		if !logged[action] {
+			t.Errorf("Expected a %s entry", action)
+		}
+	}
+
+	// stun_resist 100 on the victim: every stun is resisted
+	c2.Effects = []CombatTestEffect{
+		{EffectID: 1103, CoreEffectCode: "stun_resist", TriggerType: "passive", FactorType: "percent", Value: 100},
+	}
+	result = executeCombatSeeded(c1, c2, 3)
+	if s := result.Stats; s.Combatant1.StunApplied != 0 || s.Combatant2.TimesStunned != 0 || s.Combatant2.StunsResisted == 0 {
+		t.Errorf("Expected every stun resisted, got %+v", s)
+	}
+
+	// Diminishing returns: 50% off per stun taken, after stun_resist
+	e := &combatEngine{rules: defaultCombatRules()}
+	e.rules.StunDiminish = 50
+	victim := newCombatant(baseCombatant(2, "Victim", nil), e.rules)
+	victim.stunsTaken = 2
+	if got := e.stunChance(100, victim); got != 25 {
+		t.Errorf("Expected 25%% after two stuns, got %d", got)
+	}
+	victim.mods.StunResist = 20
+	if got := e.stunChance(100, victim); got != 20 {
+		t.Errorf("Expected 20%% with 20 stun_resist, got %d", got)
+	}
+
+	c2.Effects = nil
+	rules = defaultCombatRules()
+	rules.StunDiminish = 50
+	plain := executeCombatSeeded(c1, c2, 3)
+	result = executeCombatWith(c1, c2, CombatOptions{Seed: 3, Rules: rules})
+	if result.Stats.Combatant2.StunsResisted == 0 || result.Stats.Combatant1.StunApplied >= plain.Stats.Combatant1.StunApplied {
+		t.Errorf("Expected diminishing returns to resist stuns: %d applied vs %d without", result.Stats.Combatant1.StunApplied, plain.Stats.Combatant1.StunApplied)
+	}
+
+	if err := rules.validate(); err != nil {
+		t.Errorf("Expected valid rules, got %v", err)
+	}
+	rules.StunDiminish = 101
+	if rules.validate() == nil {
+		t.Error("Expected stunDiminish above 100 to be rejected")
+	}
+}
+
+// ── Test 136: Cleanse and dispel ──────────────────────
+
+func TestCleanseDispel(t *testing.T) {
+	e := &combatEngine{rules: defaultCombatRules()}
+	e.watch(&combatLog{})
+	owner := newCombatant(baseCombatant(1, "Cleric", nil), e.rules)
+	foe := newCombatant(baseCombatant(2, "Hexer", nil), e.rules)
+	dur := 3
+	buff := func(c *combatant, id int, code string, value int) {
+		applyModifierEffect(e, &effectContext{
+			effect:  &CombatTestEffect{EffectID: id, CoreEffectCode: code, Value: value, Duration: &dur},
+			trigger: "on_hit", source: c, target: c,
+		})
+	}
+	buff(owner, 1, "modify_damage", 20) // buff
+	buff(owner, 2, "modify_armor", -10) // debuff
+	buff(owner, 3, "anti_heal", 50)     // debuff: harmful when positive
+	owner.addDot(dotStack{kind: "bleed", effectID: 4, damage: 5, applied: e.nextApplied()}, "", 0)
+	buff(owner, 5, "modify_dodge", -30) // newest debuff
+
+	removedIDs := func(from int) []int {
+		var ids []int
+		for _, entry := range logOf(e)[from:] {
+			if entry.Action == "buff_removed" {
+				ids = append(ids, *entry.EffectID)
+			}
+		}
+		return ids
+	}
+
+	// Newest first, two at a time
+	mark := len(logOf(e))
+	applyCleanseEffect(e, &effectContext{effect: &CombatTestEffect{EffectID: 10, CoreEffectCode: "cleanse", Value: 2}, trigger: "on_turn_start", source: owner, target: owner})
+	if got := removedIDs(mark); !reflect.DeepEqual(got, []int{5, 4}) {
+		t.Errorf("Expected the two newest debuffs (5, 4) removed, got %v", got)
+	}
+	if owner.mods.DodgeChance != 0 || len(owner.dots) != 0 {
+		t.Errorf("Expected dodge restored and the bleed gone, got %+v, %d dots", owner.mods, len(owner.dots))
+	}
+	if logOf(e)[mark].Action != "cleanse" || logOf(e)[mark].Factor != 2 {
+		t.Errorf("Expected a cleanse entry for 2 removals, got %+v", logOf(e)[mark])
+	}
+
+	// Strongest first: anti_heal 50 before modify_armor -10; the buff stays
+	mark = len(logOf(e))
+	applyCleanseEffect(e, &effectContext{effect: &CombatTestEffect{EffectID: 10, CoreEffectCode: "cleanse", Value: 1, RemoveOrder: "strongest"}, trigger: "on_turn_start", source: owner, target: owner})
+	if got := removedIDs(mark); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("Expected the strongest debuff (3) removed, got %v", got)
+	}
+	if owner.mods.AntiHeal != 0 || owner.mods.ArmorModifier != -10 || owner.mods.DamageModifier != 20 {
+		t.Errorf("Unexpected modifiers after strongest cleanse: %+v", owner.mods)
+	}
+	if owner.stats.DebuffsCleansed != 3 {
+		t.Errorf("Expected 3 debuffs cleansed, got %d", owner.stats.DebuffsCleansed)
+	}
+
+	// Dispel takes the buffs only, all of them with Value 0
+	mark = len(logOf(e))
+	applyDispelEffect(e, &effectContext{effect: &CombatTestEffect{EffectID: 11, CoreEffectCode: "dispel"}, trigger: "on_hit", source: foe, target: owner})
+	if got := removedIDs(mark); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("Expected only the damage buff dispelled, got %v", got)
+	}
+	if owner.mods.DamageModifier != 0 || len(owner.buffs) != 1 || foe.stats.BuffsDispelled != 1 {
+		t.Errorf("Unexpected state after dispel: %+v, %d buffs, %d dispelled", owner.mods, len(owner.buffs), foe.stats.BuffsDispelled)
+	}
+	for _, entry := range logOf(e)[mark+1:] {
+		if entry.CharacterID != 1 {
+			t.Errorf("buff_removed should be logged on the wearer, got %+v", entry)
+		}
+	}
+
+	// In a fight: cleansing every turn keeps bleed from ever ticking twice in a row
+	c1 := baseCombatant(1, "Bleeder", []CombatTestEffect{
+		{EffectID: 12, CoreEffectCode: "bleed", TriggerType: "on_hit", FactorType: "flat", Value: 3},
+	})
+	c2 := baseCombatant(2, "Cleric", []CombatTestEffect{
+		{EffectID: 13, CoreEffectCode: "cleanse", TriggerType: "on_turn_end", TargetSelf: true},
+	})
+	result := executeCombatSeeded(c1, c2, 7)
+	for _, entry := range result.Log {
+		if entry.Action == "bleed" && entry.TriggerType == "" && entry.Factor > 3 {
+			t.Errorf("bleed should never stack past one application, got %+v", entry)
+		}
+	}
+	if result.Stats.Combatant2.DebuffsCleansed == 0 {
+		t.Error("Expected the cleanse to remove bleeds during the fight")
+	}
+
+	status, _ := checkEffectSupport(CombatTestEffect{CoreEffectCode: "dispel", TriggerType: "on_hit", Value: 1, RemoveOrder: "oldest"})
+	if status != "partial" {
+		t.Errorf("Expected an unknown remove order to be partial, got %s", status)
+	}
+}
+
+// ── Test 137: Observers see every event; metrics for tournaments ──────────────────────
+
+// countingObserver tallies the events the engine reports
+type countingObserver struct {
+	NopObserver
+	turns, attacks, procs int
+	damageTaken           map[int]int
+	buffs                 map[string]int
+	deaths                []DeathEvent
+}
+
+func (o *countingObserver) TurnStart(int, int)           { o.turns++ }
+func (o *countingObserver) Attack(AttackEvent)           { o.attacks++ }
+func (o *countingObserver) EffectProc(EffectProcEvent)   { o.procs++ }
+func (o *countingObserver) BuffChange(b BuffChangeEvent) { o.buffs[b.Change]++ }
+func (o *countingObserver) Death(d DeathEvent)           { o.deaths = append(o.deaths, d) }
+func (o *countingObserver) DamageApplied(d DamageEvent)  { o.damageTaken[d.TargetID] += d.Amount }
+
+func TestCombatObservers(t *testing.T) {
+	dur := 2
+	c1 := baseCombatant(1, "Bleeder", []CombatTestEffect{
+		{EffectID: 1201, CoreEffectCode: "bleed", TriggerType: "on_hit", FactorType: "flat", Value: 2, Duration: &dur},
+		{EffectID: 1202, CoreEffectCode: "modify_damage", TriggerType: "on_crit", FactorType: "percent", Value: 10, Duration: &dur, TargetSelf: true},
+	})
+	c2 := baseCombatant(2, "Dummy", []CombatTestEffect{
+		{EffectID: 1203, CoreEffectCode: "counterattack", TriggerType: "passive", FactorType: "percent", Value: 20},
+	})
+	plain := executeCombatSeeded(c1, c2, 11)
+
+	obs := &countingObserver{damageTaken: map[int]int{}, buffs: map[string]int{}}
+	result := executeCombatWith(c1, c2, CombatOptions{Seed: 11, Rules: defaultCombatRules(), Observers: []CombatObserver{obs}})
+	if !reflect.DeepEqual(plain.Log, result.Log) {
+		t.Fatal("an observer must not change the fight")
+	}
+
+	attacks := 0
+	for _, e := range result.Log {
+		if e.Action == "attack" || e.Action == "crit" || e.Action == "dodge" || e.Action == "counterattack" {
+			attacks++
+		}
+	}
+	if obs.attacks != attacks {
+		t.Errorf("Expected %d attack events, got %d", attacks, obs.attacks)
+	}
+	if obs.turns < result.Header.Turns {
+		t.Errorf("Expected at least %d turn starts, got %d", result.Header.Turns, obs.turns)
+	}
+	procs := 0
+	for _, s := range []CombatStats{result.Stats.Combatant1, result.Stats.Combatant2} {
+		for _, n := range s.Procs {
+			procs += n
+		}
+	}
+	if obs.procs != procs {
+		t.Errorf("Expected %d proc events, got %d", procs, obs.procs)
+	}
+	if obs.damageTaken[1] != result.Stats.Combatant1.DamageTaken || obs.damageTaken[2] != result.Stats.Combatant2.DamageTaken {
+		t.Errorf("Damage events %v don't match stats %d/%d", obs.damageTaken, result.Stats.Combatant1.DamageTaken, result.Stats.Combatant2.DamageTaken)
+	}
+	if obs.buffs[buffApplied] == 0 || obs.buffs[buffExpired] == 0 {
+		t.Errorf("Expected buffs and bleeds to be applied and expire, got %v", obs.buffs)
+	}
+	loser := result.Header.Combatant2.ID
+	if result.Header.WinnerID == loser {
+		loser = result.Header.Combatant1.ID
+	}
+	if len(obs.deaths) != 1 || obs.deaths[0].CharacterID != loser || obs.deaths[0].Turn != result.Header.Turns {
+		t.Errorf("Expected one death of %d on turn %d, got %+v", loser, result.Header.Turns, obs.deaths)
+	}
+
+	// Tournament metrics: every fight counted, damage split by cause
+	var ma, mb CombatMetrics
+	rng := rand.New(rand.NewSource(5))
+	winsA, winsB := runBuildMatch(c1, c2, 10, defaultCombatRules(), rng, &ma, &mb)
+	if ma.Fights != 10 || mb.Fights != 10 {
+		t.Errorf("Expected 10 fights per side, got %d/%d", ma.Fights, mb.Fights)
+	}
+	// A loser's counter can still take the winner down with it, so kills can exceed wins
+	if ma.Kills < winsA || mb.Kills < winsB || ma.Kills+mb.Kills > 2*10 {
+		t.Errorf("Expected a kill per win (%d/%d), got %d/%d", winsA, winsB, ma.Kills, mb.Kills)
+	}
+	if ma.DamageBySource["attack"] == 0 || ma.DamageBySource["bleed"] == 0 || mb.DamageBySource["counterattack"] == 0 {
+		t.Errorf("Expected attack, bleed and counter damage, got %v / %v", ma.DamageBySource, mb.DamageBySource)
+	}
+	if s := ma.summary(); s == nil || s.AvgTimeToKill <= 0 {
+		t.Errorf("Expected an average time to kill, got %+v", s)
+	}
+	if (&CombatMetrics{}).summary() != nil {
+		t.Error("Expected no summary without fights")
+	}
+}
+
+// ── Test 138: Fast simulation picks the same winners ──────────────────────
+
+// simulationRoster is a mix of fighters that exercises shields, DoTs, buffs,
+// stuns and proc limits, so leftovers from a reused fighter would show
+func simulationRoster() []*CombatCharacter {
+	dur := 2
+	chance := 50
+	roster := []*CombatCharacter{
+		baseCombatant(1, "Plain", nil),
+		baseCombatant(1, "Bleeder", []CombatTestEffect{
+			{EffectID: 1, CoreEffectCode: "bleed", TriggerType: "on_hit", FactorType: "flat", Value: 3, Duration: &dur, Stacking: "max_stacks", MaxStacks: 2},
+		}),
+		baseCombatant(1, "Warden", []CombatTestEffect{
+			{EffectID: 2, CoreEffectCode: "shield", TriggerType: "on_turn_start", FactorType: "flat", Value: 6, Duration: &dur, Cooldown: 3},
+			{EffectID: 3, CoreEffectCode: "modify_armor", TriggerType: "on_hit_taken", FactorType: "percent", Value: 20, Duration: &dur},
+		}),
+		baseCombatant(1, "Stunner", []CombatTestEffect{
+			{EffectID: 4, CoreEffectCode: "stun", TriggerType: "on_crit", FactorType: "percent", Value: 60, ProcChance: &chance},
+			{EffectID: 5, CoreEffectCode: "crit", TriggerType: "passive", FactorType: "percent", Value: 20},
+		}),
+		baseCombatant(1, "Cleric", []CombatTestEffect{
+			{EffectID: 6, CoreEffectCode: "heal", TriggerType: "on_turn_end", FactorType: "percent_of_max_hp", Value: 5, TargetSelf: true, MaxProcs: 4},
+			{EffectID: 7, CoreEffectCode: "cleanse", TriggerType: "on_every_other_turn", TargetSelf: true},
+		}),
+	}
+	roster[0].Armor = 30
+	roster[2].Stamina = 12
+	roster[3].Luck = 16
+	return roster
+}
+
+func TestCombatSimulatorMatchesEngine(t *testing.T) {
+	roster := simulationRoster()
+	for _, rules := range []CombatRules{defaultCombatRules(), func() CombatRules {
+		r := defaultCombatRules()
+		r.Initiative = initiativeSpeed
+		return r
+	}()} {
+		sim := newCombatSimulator(rules)
+		for seed := int64(1); seed <= 20; seed++ {
+			for i, a := range roster {
+				for j, b := range roster {
+					if i == j {
+						continue
+					}
+					c1, c2 := cloneCombatant(1, a), cloneCombatant(2, b)
+					want := executeCombatWith(c1, c2, CombatOptions{Seed: seed, Rules: rules}).Header.WinnerID
+					if got := sim.winner(c1, c2, seed); got != want {
+						t.Fatalf("%s vs %s seed %d (%s): simulator picked %d, engine %d", a.CharacterName, b.CharacterName, seed, rules.Initiative, got, want)
+					}
+				}
+			}
+		}
+	}
+
+	// Observers still see the fight, and metrics match the slow path
+	var fast, slow CombatMetrics
+	a, b := cloneCombatant(1, roster[1]), cloneCombatant(2, roster[2])
+	sim := newCombatSimulator(defaultCombatRules(), matchObservers(&fast, nil)...)
+	for seed := int64(1); seed <= 10; seed++ {
+		sim.winner(a, b, seed)
+		executeCombatWith(a, b, CombatOptions{Seed: seed, Rules: defaultCombatRules(), Observers: matchObservers(&slow, nil)})
+	}
+	if !reflect.DeepEqual(fast, slow) {
+		t.Errorf("Expected the same metrics on both paths, got %+v and %+v", fast, slow)
+	}
+
+	// Metrics read neither the log nor the timeline, so neither is built for them
+	if sim.engine.logging || sim.engine.charting {
+		t.Error("Expected a metrics-only fight to build no log entries or timeline points")
+	}
+	if allocs := testing.AllocsPerRun(20, func() { sim.winner(a, b, 3) }); allocs > 2 {
+		t.Errorf("Expected a metrics-only fight to allocate next to nothing, got %.0f allocs", allocs)
+	}
+}
+
+// BenchmarkFightFullResult is the old tournament path: a full result with log and stats per fight
+func BenchmarkFightFullResult(b *testing.B) {
+	roster := simulationRoster()
+	c1, c2 := cloneCombatant(1, roster[1]), cloneCombatant(2, roster[2])
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		executeCombatWith(c1, c2, CombatOptions{Seed: int64(i), Rules: defaultCombatRules()})
+	}
+}
+
+// BenchmarkFightSimulated is the tournament path: winner only, buffers reused
+func BenchmarkFightSimulated(b *testing.B) {
+	roster := simulationRoster()
+	c1, c2 := cloneCombatant(1, roster[1]), cloneCombatant(2, roster[2])
+	sim := newCombatSimulator(defaultCombatRules())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sim.winner(c1, c2, int64(i))
+	}
+}
+
+// BenchmarkFightSimulatedWithMetrics is the tournament path with metrics collected, as bulk and build runs do
+func BenchmarkFightSimulatedWithMetrics(b *testing.B) {
+	roster := simulationRoster()
+	c1, c2 := cloneCombatant(1, roster[1]), cloneCombatant(2, roster[2])
+	sim := newCombatSimulator(defaultCombatRules(), matchObservers(&CombatMetrics{}, &CombatMetrics{})...)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sim.winner(c1, c2, int64(i))
+	}
+}
+
+// ── Test 139: HP timeline and damage breakdown ──────────────────────
+
+func TestCombatTimelineAndBreakdown(t *testing.T) {
+	roster := simulationRoster()
+	bleeder, cleric := cloneCombatant(1, roster[1]), cloneCombatant(2, roster[4])
+	guard := baseCombatant(2, "Guard", []CombatTestEffect{
+		{EffectID: 8, CoreEffectCode: "shield", TriggerType: "on_start", FactorType: "flat", Value: 30, TargetSelf: true},
+	})
+	opts := CombatOptions{Seed: 7, Rules: defaultCombatRules()}
+
+	result := executeCombatWith(bleeder, guard, opts)
+	timeline := result.Timeline
+	if len(timeline) < 3 {
+		t.Fatalf("Expected a timeline point per half-turn, got %d points", len(timeline))
+	}
+	start := timeline[0]
+	if start.Turn != 0 || start.ActorID != 0 || len(start.Fighters) != 2 {
+		t.Fatalf("Expected the starting state first, got %+v", start)
+	}
+	if start.Fighters[0].HP != result.Header.Combatant1.MaxHP || start.Fighters[1].HP != result.Header.Combatant2.MaxHP {
+		t.Errorf("Expected both sides at max HP at the start, got %+v", start.Fighters)
+	}
+	if start.Fighters[1].Shield != 30 {
+		t.Errorf("Expected the on_start shield in the starting state, got %+v", start.Fighters[1])
+	}
+	last := timeline[len(timeline)-1]
+	if last.hpOf(1) != result.Header.Combatant1.HPEnd || last.hpOf(2) != result.Header.Combatant2.HPEnd {
+		t.Errorf("Expected the last point to match hpEnd, got %+v", last.Fighters)
+	}
+	sawBleed := false
+	for i, p := range timeline[1:] {
+		if p.ActorID == 0 || p.Turn < timeline[i].Turn {
+			t.Fatalf("Point %d: expected an actor and turns in order, got %+v", i+1, p)
+		}
+		sawBleed = sawBleed || p.Fighters[1].Bleed > 0
+	}
+	if !sawBleed {
+		t.Error("Expected the Guard's bleed on the timeline")
+	}
+
+	// DoT ticks show under their effect but aren't damage dealt
+	dealt := 0
+	for _, src := range result.Breakdown.Combatant1 {
+		dealt += src.Damage
+	}
+	bleed := result.Breakdown.Combatant1["effect:1"].Damage
+	if bleed == 0 || dealt-bleed != result.Stats.Combatant1.DamageDealt {
+		t.Errorf("Expected breakdown minus bleed (%d - %d) to equal damage dealt %d", dealt, bleed, result.Stats.Combatant1.DamageDealt)
+	}
+	if result.Breakdown.Combatant1["attack"].Damage+result.Breakdown.Combatant1["crit"].Damage == 0 {
+		t.Errorf("Expected base attacks in the breakdown, got %+v", result.Breakdown.Combatant1)
+	}
+
+	healed := executeCombatWith(bleeder, cleric, opts)
+	heal := healed.Breakdown.Combatant2["effect:6"]
+	if heal.Healing == 0 || heal.Healing != healed.Stats.Combatant2.HealingDone || heal.Overheal != healed.Stats.Combatant2.Overheal {
+		t.Errorf("Expected the heal effect to hold all healing %d (overheal %d), got %+v",
+			healed.Stats.Combatant2.HealingDone, healed.Stats.Combatant2.Overheal, heal)
+	}
+
+	// Simulations average both over their fights
+	sim := simulateCombat(bleeder, cleric, 50, opts)
+	for _, side := range []SimulationSideStat{sim.Combatant1, sim.Combatant2} {
+		curve := side.AvgHPByTurn
+		if len(curve) < 2 || curve[0] != float64(side.MaxHP) {
+			t.Fatalf("%s: expected the HP curve to start at max HP %d, got %v", side.Name, side.MaxHP, curve)
+		}
+		if curve[len(curve)-1] != side.AvgHPRemaining {
+			t.Errorf("%s: expected the curve to end at the average HP left %.2f, got %.2f", side.Name, side.AvgHPRemaining, curve[len(curve)-1])
+		}
+	}
+	if got := sim.Combatant2.Breakdown["effect:6"].Healing; got != sim.Combatant2.Stats.HealingDone {
+		t.Errorf("Expected summed heal breakdown %d to equal summed healing %d", got, sim.Combatant2.Stats.HealingDone)
+	}
+	if len(sim.Combatant1.AvgBreakdown) == 0 {
+		t.Error("Expected an averaged breakdown")
+	}
+}
+
+// ── Test 140: Narration ──────────────────────
+
+func TestNarrateCombat(t *testing.T) {
+	result := &CombatResult{
+		Header: CombatResultHeader{
+			WinnerID: 1, Turns: 3,
+			Combatant1: CombatantSummary{ID: 1, Name: "Rookbridge Brute", MaxHP: 120, HPEnd: 40},
+			Combatant2: CombatantSummary{ID: 2, Name: "Marsh Wolf", MaxHP: 90, HPEnd: 0},
+		},
+		Log: []CombatLogEntry{
+			{Turn: 0, CharacterID: 2, TargetID: 2, Action: "shield", Factor: 10, EffectID: intPtr(7), TriggerType: "on_start", BuffType: "shield"},
+			{Turn: 3, CharacterID: 1, Action: "initiative", Factor: 1},
+			{Turn: 3, CharacterID: 1, TargetID: 2, Action: "crit", Factor: 42, Absorbed: 10},
+			{Turn: 3, CharacterID: 1, TargetID: 2, Action: "bleed", Factor: 6, EffectID: intPtr(12), TriggerType: "on_crit"},
+			{Turn: 3, CharacterID: 2, TargetID: 2, SourceID: 1, Action: "buff", Factor: -20, EffectID: intPtr(3), TriggerType: "on_crit", Duration: intPtr(2), BuffType: "modify_armor"},
+			{Turn: 3, CharacterID: 2, Action: "bleed", Factor: 6},
+			{Turn: 3, CharacterID: 2, Action: "buff_expire", Factor: 20, EffectID: intPtr(9), BuffType: "modify_armor"},
+		},
+	}
+	// Log effect IDs are per fighter: both have an effect 3 and a 12
+	brute := baseCombatant(1, "Rookbridge Brute", []CombatTestEffect{
+		{EffectID: 3, SourceEffectID: 301, CoreEffectCode: "modify_armor", TriggerType: "on_crit", Value: -20},
+		{EffectID: 12, SourceEffectID: 302, CoreEffectCode: "bleed", TriggerType: "on_crit", Value: 6},
+	})
+	wolf := baseCombatant(2, "Marsh Wolf", []CombatTestEffect{
+		{EffectID: 3, SourceEffectID: 303, CoreEffectCode: "dodge", TriggerType: "passive", Value: 5},
+		{EffectID: 7, SourceEffectID: 304, CoreEffectCode: "shield", TriggerType: "on_start", Value: 10},
+		{EffectID: 12, SourceEffectID: 305, CoreEffectCode: "heal", TriggerType: "on_hit", Value: 5},
+	})
+	names := map[int]string{301: "Sunder", 302: "Serrated Edge", 303: "Light Feet", 305: "Lick Wounds"}
+	got := narrateCombat(result, []*CombatCharacter{brute, wolf}, names)
+	want := []string{
+		"Start: Marsh Wolf gains a 10-point shield (effect #7)",
+		"Turn 3: Rookbridge Brute crits for 42 (10 absorbed), bleeding 6 (Serrated Edge)",
+		"Turn 3: Marsh Wolf gains armor -20 from Rookbridge Brute for 2 turns (Sunder), bleeds for 6, armor +20 wears off",
+		"Rookbridge Brute wins after 3 turns with 40/120 HP left",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Narration mismatch:\n got %q\nwant %q", got, want)
+	}
+
+	// Every action the engine logs has its own wording
+	n := &combatNarrator{}
+	for _, action := range []string{"attack", "crit", "dodge", "stun", "stunned", "stun_resisted", "stun_immune", "stun_immunity",
+		"cleanse", "dispel", "buff_removed", "bleed", "poison", "burn", "counterattack", "heal", "lifesteal", "reflect_damage",
+		"armor_penetration", "buff", "buff_expire", "shield", "shield_break", "damage"} {
+		if p := n.phrase(CombatLogEntry{Action: action, Factor: 5}); p == action+" (5)" {
+			t.Errorf("Expected %s to have its own wording", action)
+		}
+	}
+}
+
+// ── Test 141: Stored characters as test combatants ──────────────────────
+
+func TestTestCombatantSources(t *testing.T) {
+	code := func(s string) *string { return &s }
+	yes := true
+	r := &testCombatantResolver{
+		loaded: true,
+		talents: map[int]TalentInfo{
+			1: {TalentID: 1, MaxPoints: 3, EffectID: intPtr(10), Factor: intPtr(5)},
+			2: {TalentID: 2, MaxPoints: 2, PerkSlot: &yes},
+		},
+		effects: map[int]Effect{
+			10: {ID: 10, CoreEffectCode: code("crit")},
+			11: {ID: 11, CoreEffectCode: code("bleed"), TriggerType: code("on_hit"), FactorType: code("flat")},
+		},
+		perks: map[int]Perk{7: {ID: 7, Effect1ID: intPtr(11), Factor1: intPtr(4)}},
+	}
+
+	// An enemy spends all of its points, so its perk is active
+	enemy := r.enemy(2, "Marsh Wolf", 8, 0, 9, 4, 3, 2, 6, []EnemyTalent{
+		{TalentID: 2, Points: 2, TalentOrder: 2, PerkID: intPtr(7)},
+		{TalentID: 1, Points: 3, TalentOrder: 1},
+	})
+	if enemy.CharacterID != 2 || enemy.CharacterName != "Marsh Wolf" || enemy.Stamina != 1 {
+		t.Errorf("Expected the enemy row's identity and a stamina floor of 1, got %+v", enemy)
+	}
+	if len(enemy.Effects) != 2 || enemy.Effects[0].CoreEffectCode != "crit" || enemy.Effects[0].Value != 15 ||
+		enemy.Effects[1].CoreEffectCode != "bleed" || enemy.Effects[1].Value != 4 {
+		t.Errorf("Expected crit 15 then the perk's bleed 4, got %+v", enemy.Effects)
+	}
+
+	// A build early in its career hasn't maxed the perk slot yet
+	build := &Build{BuildName: "Glass", Stamina: 10, Talents: []BuildTalent{
+		{TalentID: 1, Points: 3, TalentOrder: 1}, {TalentID: 2, Points: 2, TalentOrder: 2, PerkID: intPtr(7)},
+	}}
+	if early := snapshotBuild(1, build, 4, r.talents, r.effects, r.perks, nil); len(early.Effects) != 1 {
+		t.Errorf("Expected only the crit talent on day 4, got %+v", early.Effects)
+	}
+
+	// A hand-made side's talent picks come after its own effects, perk included once maxed
+	picked := r.custom(CombatTestCombatant{Name: "Picker", Stamina: 5,
+		Effects: []CombatTestEffect{{EffectID: 4, CoreEffectCode: "dodge", TriggerType: "passive", Value: 5}},
+		Talents: []BuildTalent{{TalentID: 1, Points: 2, TalentOrder: 1}, {TalentID: 2, Points: 2, TalentOrder: 2, PerkID: intPtr(7)}},
+	}, 1)
+	if len(picked.Effects) != 3 || picked.Effects[1].EffectID != 5 || picked.Effects[1].CoreEffectCode != "crit" || picked.Effects[1].Value != 10 ||
+		picked.Effects[2].EffectID != 6 || picked.Effects[2].SourceEffectID != 11 {
+		t.Errorf("Expected dodge 4, then crit 10 as 5 and the perk's bleed as 6, got %+v", picked.Effects)
+	}
+	if err := (CombatTestCombatant{Stamina: 5, Talents: []BuildTalent{{TalentID: 1, Points: -1}}}).validate(); err == nil {
+		t.Error("Expected negative talent points to be rejected")
+	}
+
+	// Hand-made sides pass through; conflicting or bad references are rejected before any query
+	custom, err := r.character(CombatTestCombatant{Name: "Dummy", Stamina: 5}, 1)
+	if err != nil || custom.CharacterName != "Dummy" || custom.CharacterID != 1 {
+		t.Errorf("Expected a hand-made combatant, got %+v, %v", custom, err)
+	}
+	if _, err := r.character(CombatTestCombatant{EnemyID: intPtr(1), BuildID: new(int64)}, 1); err == nil {
+		t.Error("Expected an error for two references")
+	}
+	if _, err := r.character(CombatTestCombatant{BuildID: new(int64), BuildDay: -1}, 1); err == nil {
+		t.Error("Expected an error for a negative build day")
+	}
+}
+
+// ── Test 142: Equipment loadouts ────────────────────────────────────────
+
+func TestEquipment(t *testing.T) {
+	code := func(s string) *string { return &s }
+	effects := map[int]Effect{
+		20: {ID: 20, CoreEffectCode: code("lifesteal"), Factor: 10},
+		21: {ID: 21, CoreEffectCode: code("crit"), Factor: 3},
+	}
+	items := map[int]Item{
+		1: {ID: 1, Name: "Cleaver", Type: "weapon", Strength: intPtr(4), MinDamage: intPtr(3), MaxDamage: intPtr(7),
+			EffectID: intPtr(20), EffectFactor: intPtr(6), Socket: true},
+		2: {ID: 2, Name: "Ruby", Type: "gem", Luck: intPtr(2), EffectID: intPtr(21)},
+		3: {ID: 3, Name: "Iron Cap", Type: "head", Armor: intPtr(5), Stamina: intPtr(-20)},
+		4: {ID: 4, Name: "Old Helm", Type: "head", Armor: intPtr(9)},
+		5: {ID: 5, Name: "Tonic", Type: "potion"},
+	}
+
+	c := &CombatCharacter{CharacterID: 1, Strength: 10, Stamina: 8, MinDamage: 1, MaxDamage: 2,
+		Effects: []CombatTestEffect{{EffectID: 1, CoreEffectCode: "stun"}}}
+	shared := c.Effects
+	loadout := []EquippedItem{{ItemID: 1, GemID: intPtr(2)}, {ItemID: 3}}
+	if err := validateLoadout(loadout, items); err != nil {
+		t.Fatalf("Expected a valid loadout, got %v", err)
+	}
+	equipCharacter(c, loadout, items, effects)
+	if c.Strength != 14 || c.Luck != 2 || c.Armor != 5 || c.MinDamage != 4 || c.MaxDamage != 9 || c.Stamina != 1 {
+		t.Errorf("Expected item and gem stats summed with a stamina floor of 1, got %+v", c)
+	}
+	if len(c.Effects) != 3 || c.Effects[1].EffectID != 2 || c.Effects[1].Value != 6 ||
+		c.Effects[2].EffectID != 3 || c.Effects[2].CoreEffectCode != "crit" || c.Effects[2].Value != 3 {
+		t.Errorf("Expected the weapon's lifesteal at its item factor and the gem's crit at the effect's, got %+v", c.Effects)
+	}
+	if len(shared) != 1 {
+		t.Error("Expected equipping to leave the original effects slice alone")
+	}
+
+	bad := map[string][]EquippedItem{
+		"unknown item":    {{ItemID: 99}},
+		"not wearable":    {{ItemID: 5}},
+		"slot taken":      {{ItemID: 3}, {ItemID: 4}},
+		"no socket":       {{ItemID: 3, GemID: intPtr(2)}},
+		"gem not a gem":   {{ItemID: 1, GemID: intPtr(4)}},
+		"gem not found":   {{ItemID: 1, GemID: intPtr(99)}},
+		"weapon as a gem": {{ItemID: 1, GemID: intPtr(1)}},
+	}
+	for name, l := range bad {
+		if err := validateLoadout(l, items); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+
+	// A build's equipment is worn unscaled on any day
+	build := &Build{BuildName: "Geared", Strength: 10, Stamina: 10, Equipment: []EquippedItem{{ItemID: 4}}}
+	if snap := snapshotBuild(1, build, 10, nil, effects, nil, items); snap.Armor != 9 {
+		t.Errorf("Expected the helm's 9 armor on day 10, got %d", snap.Armor)
+	}
+}
+
+// ── Test 143: Blessings and potions ─────────────────────────────────────
+
+func TestBlessingsAndPotions(t *testing.T) {
+	code := func(s string) *string { return &s }
+	yes := true
+	effects := map[int]Effect{
+		30: {ID: 30, CoreEffectCode: code("heal"), FactorType: code("flat"), TargetSelf: &yes, Factor: 25,
+			TriggerType: code("on_hit"), ConditionType: code("hp_above_percent"), ConditionValue: intPtr(90)},
+		31: {ID: 31, CoreEffectCode: code("modify_armor"), FactorType: code("flat")},
+	}
+	perks := map[int]Perk{
+		1: {ID: 1, Name: "Grace", IsBlessing: true, Effect1ID: intPtr(31), Factor1: intPtr(12)},
+		2: {ID: 2, Name: "Frenzy", Effect1ID: intPtr(31), Factor1: intPtr(5)},
+	}
+	items := map[int]Item{
+		5: {ID: 5, Name: "Tonic", Type: "potion", EffectID: intPtr(30), EffectFactor: intPtr(50)},
+		6: {ID: 6, Name: "Empty Vial", Type: "potion"},
+		7: {ID: 7, Name: "Cap", Type: "head"},
+	}
+
+	for name, ids := range map[string][]int{"unknown": {9}, "not a blessing": {2}, "twice": {1, 1}, "too many": {1, 1, 1, 1}} {
+		if err := validateBlessings(ids, perks); err == nil {
+			t.Errorf("blessings %s: expected a validation error", name)
+		}
+	}
+	for name, p := range map[string]CombatPotion{"unknown": {ItemID: 9}, "no effect": {ItemID: 6}, "not a potion": {ItemID: 7},
+		"threshold": {ItemID: 5, HPBelowPercent: 101}} {
+		if err := validatePotions([]CombatPotion{p}, items); err == nil {
+			t.Errorf("potion %s: expected a validation error", name)
+		}
+	}
+
+	c := baseCombatant(1, "Drinker", nil)
+	blessCharacter(c, []int{1}, perks, effects)
+	stockPotions(c, []CombatPotion{{ItemID: 5, HPBelowPercent: 50}}, items, effects)
+	if len(c.Effects) != 2 || c.Effects[0].CoreEffectCode != "modify_armor" || c.Effects[0].Value != 12 {
+		t.Fatalf("Expected the blessing's armor then the potion, got %+v", c.Effects)
+	}
+	potion := c.Effects[1]
+	if !potion.Consumable || potion.TriggerType != "on_turn_start" || potion.Value != 50 || potion.ConditionType != nil ||
+		potion.Condition == nil || potion.Condition.Value != 50 {
+		t.Errorf("Expected a one-shot turn-start heal gated on the threshold alone, got %+v", potion)
+	}
+
+	// Hits of 20 take the Drinker below half on the third; it drinks once, on its next turn
+	result := executeCombatWith(c, baseCombatant(2, "Brute", nil), CombatOptions{Seed: 3, Rules: defaultCombatRules()})
+	drunk := 0
+	for i, entry := range result.Log {
+		if entry.Action != "potion" {
+			continue
+		}
+		drunk++
+		if entry.CharacterID != 1 || i+1 >= len(result.Log) || result.Log[i+1].Action != "heal" {
+			t.Errorf("Expected the Drinker's potion followed by its heal, got %+v", result.Log[i:])
+		}
+	}
+	if drunk != 1 || result.Stats.Combatant1.PotionsUsed != 1 {
+		t.Errorf("Expected one potion drunk, got %d logged and %d counted", drunk, result.Stats.Combatant1.PotionsUsed)
+	}
+}
+
+// ── Test 144: Golden combat scenarios ───────────────────────────────────
+//
+// Every testdata/combat/<name>.json scenario (two hand-made combatants, a
+// seed and a number of fights) is fought once with its seed and simulated
+// for win rates; the log and the rates must match <name>.golden. After an
+// intended balance change, regenerate them with
+//
+//	go test -run TestGoldenCombats -update
+//
+// and review the .golden diffs like any other change.
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files under testdata/combat")
+
+// goldenScenario is one testdata/combat scenario file
+type goldenScenario struct {
+	CombatSimulationRequest
+	Description string `json:"description"`
+}
+
+func TestGoldenCombats(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "combat", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("Expected scenarios under testdata/combat, got %v (%v)", paths, err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sc goldenScenario
+			if err := json.Unmarshal(raw, &sc); err != nil {
+				t.Fatalf("Invalid scenario: %v", err)
+			}
+			if sc.Seed == nil {
+				t.Fatal("A scenario needs a seed")
+			}
+			got := goldenReport(&sc)
+
+			golden := strings.TrimSuffix(path, ".json") + ".golden"
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("No golden file (run with -update to create it): %v", err)
+			}
+			if diff := lineDiff(string(want), got); diff != "" {
+				t.Errorf("Outcome changed from %s (- golden, + now); rerun with -update if intended:\n%s", golden, diff)
+			}
+		})
+	}
+}
+
+// goldenReport fights the scenario once and simulates it, one line per fact
+func goldenReport(sc *goldenScenario) string {
+	c1, c2 := sc.Combatant1.character(1), sc.Combatant2.character(2)
+	opts := CombatOptions{Seed: *sc.Seed, Rules: defaultCombatRules()}
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n", sc.Description)
+	result := executeCombatWith(c1, c2, opts)
+	h := result.Header
+	fmt.Fprintf(&b, "fight seed=%d winner=%d turns=%d timedOut=%v\n", h.Seed, h.WinnerID, h.Turns, h.TimedOut)
+	for _, s := range []CombatantSummary{h.Combatant1, h.Combatant2} {
+		fmt.Fprintf(&b, "  #%d %s hp %d/%d\n", s.ID, s.Name, s.HPEnd, s.MaxHP)
+	}
+	for _, entry := range result.Log {
+		b.WriteString(goldenEntry(entry))
+		b.WriteByte('\n')
+	}
+
+	fights := sc.Fights
+	if fights <= 0 {
+		fights = defaultSimulationFights
+	}
+	sim := simulateCombat(c1, c2, fights, opts)
+	fmt.Fprintf(&b, "simulation fights=%d avgTurns=%.2f timeouts=%d\n", sim.Fights, sim.AvgTurns, sim.Timeouts)
+	for _, s := range []SimulationSideStat{sim.Combatant1, sim.Combatant2} {
+		fmt.Fprintf(&b, "  #%d %s wins=%d rate=%.4f [%.4f, %.4f] avgHp=%.2f\n",
+			s.ID, s.Name, s.Wins, s.WinRate, s.WinRateLow, s.WinRateHigh, s.AvgHPRemaining)
+	}
+	return b.String()
+}
+
+// goldenEntry prints a log entry with only the fields it sets
+func goldenEntry(e CombatLogEntry) string {
+	line := fmt.Sprintf("T%d #%d %s", e.Turn, e.CharacterID, e.Action)
+	if e.TargetID != 0 {
+		line += fmt.Sprintf(" ->#%d", e.TargetID)
+	}
+	line += fmt.Sprintf(" %d", e.Factor)
+	if e.EffectID != nil {
+		line += fmt.Sprintf(" eff=%d", *e.EffectID)
+	}
+	if e.TriggerType != "" {
+		line += " " + e.TriggerType
+	}
+	if e.BuffType != "" {
+		line += " buff=" + e.BuffType
+	}
+	if e.Duration != nil {
+		line += fmt.Sprintf(" dur=%d", *e.Duration)
+	}
+	if e.Absorbed != 0 {
+		line += fmt.Sprintf(" absorbed=%d", e.Absorbed)
+	}
+	if e.Overheal != 0 {
+		line += fmt.Sprintf(" overheal=%d", e.Overheal)
+	}
+	if e.SourceID != 0 {
+		line += fmt.Sprintf(" from=#%d", e.SourceID)
+	}
+	return line
+}
+
+// lineDiff returns the changed lines between want and got with two lines of
+// context, or "" when they are equal
+func lineDiff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []string // " ", "-" or "+" then the line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, "- "+a[i])
+			i++
+		default:
+			ops = append(ops, "+ "+b[j])
+			j++
+		}
+	}
+	const context = 2
+	var out []string
+	last := -1
+	for k, op := range ops {
+		if op[0] == ' ' {
+			continue
+		}
+		from := max(k-context, last+1)
+		if last >= 0 && from > last+1 {
+			out = append(out, "  ...")
+		}
+		for c := from; c < k; c++ {
+			out = append(out, ops[c])
+		}
+		out = append(out, op)
+		last = k
+		for c := k + 1; c <= k+context && c < len(ops) && ops[c][0] == ' '; c++ {
+			out = append(out, ops[c])
+			last = c
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// ── Test 145: Fuzzed test requests keep the engine's invariants ─────────
+//
+// go test -run '^$' -fuzz FuzzCombatTestRequest explores further; the seed
+// corpus (the golden scenarios and the edge cases below) runs with every go test.
+
+func FuzzCombatTestRequest(f *testing.F) {
+	paths, _ := filepath.Glob(filepath.Join("testdata", "combat", "*.json"))
+	for _, path := range paths {
+		if raw, err := os.ReadFile(path); err == nil {
+			f.Add(raw)
+		}
+	}
+	f.Add([]byte(`{"combatant1":{"stamina":0,"armor":-50},"combatant2":{"minDamage":30,"maxDamage":2},"seed":1}`))
+	f.Add([]byte(`{"combatant1":{"stamina":1,"effects":[{"effectId":1,"coreEffectCode":"heal","triggerType":"on_turn_start","factorType":"percent_of_max_hp","value":100000,"targetSelf":true}]},"combatant2":{"stamina":1}}`))
+	f.Add([]byte(`{"combatant1":{"stamina":3,"effects":[{"effectId":1,"coreEffectCode":"reflect_damage","triggerType":"passive","value":900}]},"combatant2":{"stamina":3,"strength":2000000000}}`))
+	f.Add([]byte(`{"combatant1":{"stamina":100000,"strength":100000,"luck":100000,"minDamage":100000,"maxDamage":100000,"effects":[
+		{"effectId":1,"coreEffectCode":"modify_damage","triggerType":"on_hit","value":100000,"duration":30},
+		{"effectId":2,"coreEffectCode":"lifesteal","triggerType":"passive","value":100000},
+		{"effectId":3,"coreEffectCode":"double_attack","triggerType":"passive","value":100}]},
+		"combatant2":{"stamina":1,"armor":100000,"effects":[
+		{"effectId":1,"coreEffectCode":"reflect_damage","triggerType":"passive","value":100000},
+		{"effectId":2,"coreEffectCode":"heal","triggerType":"on_hit_taken","factorType":"percent_of_damage_taken","value":100000,"targetSelf":true},
+		{"effectId":3,"coreEffectCode":"poison","triggerType":"on_hit_taken","factorType":"flat","value":100000,"duration":100000,"stacking":"stack"}]},"seed":9}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var req CombatTestRequest
+		if json.Unmarshal(data, &req) != nil {
+			return
+		}
+		// Stored characters need the DB; the rest is what the handlers see
+		for _, c := range []CombatTestCombatant{req.Combatant1, req.Combatant2} {
+			if c.EnemyID != nil || c.PendingEnemyID != nil || c.BuildID != nil ||
+				len(c.Equipment) > 0 || len(c.Blessings) > 0 || len(c.Potions) > 0 {
+				return
+			}
+		}
+		c1, c2, err := buildTestCombatants(&req)
+		if err != nil {
+			return // the handlers answer 400
+		}
+		seed := int64(0)
+		if req.Seed != nil {
+			seed = *req.Seed
+		}
+		result := executeCombatWith(c1, c2, CombatOptions{Seed: seed, Rules: defaultCombatRules()})
+		for _, v := range combatInvariantViolations(result) {
+			t.Error(v)
+		}
+	})
+}
+
+// combatInvariantViolations lists what in a 1v1 result breaks the engine's invariants
+func combatInvariantViolations(r *CombatResult) []string {
+	var out []string
+	h := r.Header
+	ids := map[int]bool{h.Combatant1.ID: true, h.Combatant2.ID: true}
+	if !ids[h.WinnerID] {
+		out = append(out, fmt.Sprintf("winner %d is neither fighter", h.WinnerID))
+	}
+	for _, s := range []CombatantSummary{h.Combatant1, h.Combatant2} {
+		if s.HPEnd < 0 || s.HPEnd > s.MaxHP {
+			out = append(out, fmt.Sprintf("fighter %d ends with %d/%d HP", s.ID, s.HPEnd, s.MaxHP))
+		}
+	}
+	maxHP := map[int]int{h.Combatant1.ID: h.Combatant1.MaxHP, h.Combatant2.ID: h.Combatant2.MaxHP}
+	for _, p := range r.Timeline {
+		for _, f := range p.Fighters {
+			if f.HP > maxHP[f.ID] {
+				out = append(out, fmt.Sprintf("turn %d: fighter %d at %d/%d HP", p.Turn, f.ID, f.HP, maxHP[f.ID]))
+			}
+		}
+	}
+
+	// Turns never go back and never pass the header's count
+	turn := 0
+	counts := map[int]map[string]int{}
+	dodged := map[int]int{}
+	for i, e := range r.Log {
+		if e.Turn < turn || e.Turn > h.Turns {
+			out = append(out, fmt.Sprintf("log entry %d: turn %d after turn %d (fight lasted %d)", i, e.Turn, turn, h.Turns))
+		}
+		turn = e.Turn
+		if counts[e.CharacterID] == nil {
+			counts[e.CharacterID] = map[string]int{}
+		}
+		counts[e.CharacterID][e.Action]++
+		if e.Action == "dodge" {
+			dodged[e.TargetID]++
+		}
+	}
+
+	// Stats agree with the log
+	for id := range ids {
+		s, c := r.StatsFor(id), counts[id]
+		for name, pair := range map[string][2]int{
+			"attacks":       {s.Attacks, c["attack"] + c["crit"]},
+			"critHits":      {s.CritHits, c["crit"]},
+			"attacksDodged": {s.AttacksDodged, c["dodge"]},
+			"dodgedAttacks": {s.DodgedAttacks, dodged[id]},
+			"counterHits":   {s.CounterHits, c["counterattack"]},
+			"potionsUsed":   {s.PotionsUsed, c["potion"]},
+		} {
+			if pair[0] != pair[1] {
+				out = append(out, fmt.Sprintf("fighter %d: %s is %d but the log has %d", id, name, pair[0], pair[1]))
+			}
+		}
+	}
+	return out
+}
+
+// ── Test 146: Hand-made combatant validation ────────────────────────────
+
+func TestTestCombatantValidation(t *testing.T) {
+	ok := CombatTestCombatant{Stamina: 10, Strength: 5, MinDamage: 2, MaxDamage: 6, Effects: []CombatTestEffect{
+		{EffectID: 1, CoreEffectCode: "modify_armor", TriggerType: "on_hit", Value: -30, Duration: intPtr(2)},
+	}}
+	if err := ok.validate(); err != nil {
+		t.Fatalf("Expected a debuff with a negative value to pass, got %v", err)
+	}
+	bad := map[string]func(c *CombatTestCombatant){
+		"no stamina":      func(c *CombatTestCombatant) { c.Stamina = 0 },
+		"negative armor":  func(c *CombatTestCombatant) { c.Armor = -5 },
+		"huge strength":   func(c *CombatTestCombatant) { c.Strength = maxTestStat + 1 },
+		"inverted damage": func(c *CombatTestCombatant) { c.MinDamage, c.MaxDamage = 9, 3 },
+		"negative heal": func(c *CombatTestCombatant) {
+			c.Effects = []CombatTestEffect{{EffectID: 2, CoreEffectCode: "heal", TriggerType: "on_hit", Value: -50}}
+		},
+		"huge value":        func(c *CombatTestCombatant) { c.Effects[0].Value = maxTestEffectValue + 1 },
+		"negative duration": func(c *CombatTestCombatant) { c.Effects[0].Duration = intPtr(-1) },
+		"negative decay":    func(c *CombatTestCombatant) { c.Effects[0].Decay = -2 },
+		"proc chance":       func(c *CombatTestCombatant) { c.Effects[0].ProcChance = intPtr(150) },
+		"too many effects":  func(c *CombatTestCombatant) { c.Effects = make([]CombatTestEffect, maxTestEffects+1) },
+	}
+	for name, breakIt := range bad {
+		c := ok
+		c.Effects = append([]CombatTestEffect(nil), ok.Effects...)
+		breakIt(&c)
+		if _, _, err := buildTestCombatants(&CombatTestRequest{Combatant1: c, Combatant2: ok}); err == nil {
+			t.Errorf("%s: expected the request to be rejected", name)
+		}
+	}
+}
+
+// ── Test 147: Passive targets and legacy condition subjects ─────────────
+
+func TestPassiveTargetsAndConditionSubjects(t *testing.T) {
+	e := &combatEngine{rules: defaultCombatRules()}
+	owner := newCombatant(baseCombatant(1, "Hexer", []CombatTestEffect{
+		{EffectID: 1, CoreEffectCode: "dodge", TriggerType: "passive", Value: 10},
+		{EffectID: 2, CoreEffectCode: "modify_armor", TriggerType: "passive", Value: 5, TargetSelf: true},
+		{EffectID: 3, CoreEffectCode: "anti_heal", TriggerType: "passive", Value: 50},
+		{EffectID: 4, CoreEffectCode: "modify_damage", TriggerType: "passive", Value: -20},
+		{EffectID: 5, CoreEffectCode: "modify_heal", TriggerType: "passive", Value: 15},
+		{EffectID: 6, CoreEffectCode: "modify_crit", TriggerType: "passive", Value: -5},
+	}), e.rules)
+	foe := newCombatant(baseCombatant(2, "Victim", nil), e.rules)
+	foe.team = 1
+	e.teams = [2][]*combatant{{owner}, {foe}}
+	e.applyPassives(owner)
+	// dodge, armor and heal stay on their owner as they always have; modify_damage
+	// without targetSelf never applied; the newer auras land on the enemy
+	if owner.mods.DodgeChance != 10 || owner.mods.ArmorModifier != 5 || owner.mods.HealModifier != 15 ||
+		owner.mods.AntiHeal != 0 || owner.mods.DamageModifier != 0 || owner.mods.CritChance != 0 {
+		t.Errorf("Expected dodge, armor and heal on the owner, got %+v", owner.mods)
+	}
+	if foe.mods.AntiHeal != 50 || foe.mods.CritChance != -5 || foe.mods.DamageModifier != 0 || foe.mods.HealModifier != 0 {
+		t.Errorf("Expected anti_heal and the crit debuff on the enemy, got %+v", foe.mods)
+	}
+	if status, _ := checkEffectSupport(CombatTestEffect{CoreEffectCode: "modify_damage", TriggerType: "passive"}); status != "unsupported" {
+		t.Errorf("Expected passive modify_damage without targetSelf reported unsupported, got %s", status)
+	}
+
+	// The legacy condition reads the owner's HP, except on on_hit and on_crit
+	owner.hp, foe.hp = owner.maxHP/5, foe.maxHP
+	low := &CombatTestEffect{ConditionType: strPtr("hp_below_percent"), ConditionValue: intPtr(50)}
+	for trigger, want := range map[string]bool{"on_turn_start": true, "on_hit_taken": true, "on_hit": false, "on_crit": false} {
+		cc := &conditionContext{owner: owner, opponent: foe, target: foe, trigger: trigger}
+		if got := checkCondition(low, cc); got != want {
+			t.Errorf("%s: expected the condition to be %v, got %v", trigger, want, got)
+		}
+	}
+}
+
+// ── Test 148: A fighter its DoTs kill doesn't act ──────────────────────
+
+func TestDotDeathEndsTurn(t *testing.T) {
+	e := &combatEngine{rules: defaultCombatRules(), rng: rand.New(rand.NewSource(1))}
+	e.watch(&combatLog{})
+	victim := newCombatant(baseCombatant(1, "Victim", nil), e.rules)
+	foe := newCombatant(baseCombatant(2, "Poisoner", nil), e.rules)
+	foe.team = 1
+	e.teams = [2][]*combatant{{victim}, {foe}}
+	e.fighters = []*combatant{victim, foe}
+	e.turn = 1
+
+	victim.hp = 5
+	victim.addDot(dotStack{kind: "poison", effectID: 1, damage: 10, ignoreArmor: true, source: foe}, "", 0)
+	e.executeTurn(victim, foe)
+	if foe.hp != foe.maxHP {
+		t.Errorf("Expected the dead fighter not to attack, foe has %d/%d HP", foe.hp, foe.maxHP)
+	}
+	for _, l := range logOf(e) {
+		if l.CharacterID == 1 && (l.Action == "attack" || l.Action == "crit" || l.Action == "dodge") {
+			t.Errorf("Expected no attack from the dead fighter, got %+v", l)
+		}
+	}
+	if w := e.winnerAfter(victim); w != 2 {
+		t.Errorf("Expected the poisoner's team to win, got team %d", w)
+	}
+}
+
+// helper for condition pointers
+func strPtr(s string) *string { return &s }
+func intPtr(i int) *int       { return &i }
+
+// logOf returns what a hand-built engine's combat log recorded
+func logOf(e *combatEngine) []CombatLogEntry {
+	return e.observers[0].(*combatLog).entries
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"math"
+	"strconv"
+)
+
+// ── Timeline and breakdown ──────────────────────────────────────────────────
+//
+// The tester charts a fight from its timeline: every fighter's HP, shield
+// points and pending DoT damage once on_start has fired and after each
+// fighter's turn (a half-turn in a 1v1 fight). The breakdown splits what each
+// fighter dealt and healed by source — attack, crit, counterattack,
+// reflect_damage and lifesteal for the built-in sources, effect:<id> for
+// effects. A DoT tick counts for the effect of its oldest stack, like its
+// damage counts for that stack's applier. Both come from chartObserver, so
+// fights played without observers (the tournament simulator) don't pay for them.
+
+// TimelinePoint is every fighter's state at one moment of a fight
+type TimelinePoint struct {
+	Turn     int            `json:"turn"`
+	ActorID  int            `json:"actorId,omitempty"` // whose turn just ended, 0 for the starting state
+	Fighters []FighterState `json:"fighters"`          // team 1 then team 2, in header order
+}
+
+// FighterState is one fighter's HP, shield points and the DoT damage its
+// stacks will deal on the next tick (before armor)
+type FighterState struct {
+	ID     int `json:"id"`
+	HP     int `json:"hp"`
+	Shield int `json:"shield"`
+	Bleed  int `json:"bleed"`
+	Poison int `json:"poison,omitempty"`
+	Burn   int `json:"burn,omitempty"`
+}
+
+// SourceTotals is what one source dealt and healed over a fight
+type SourceTotals struct {
+	Damage   int `json:"damage,omitempty"`   // HP damage
+	Absorbed int `json:"absorbed,omitempty"` // damage the target's shields soaked up
+	Healing  int `json:"healing,omitempty"`  // effective healing, overheal excluded
+	Overheal int `json:"overheal,omitempty"`
+}
+
+// DamageBreakdown is one fighter's totals keyed by source
+type DamageBreakdown map[string]SourceTotals
+
+// Add accumulates another fight's breakdown
+func (b *DamageBreakdown) Add(o DamageBreakdown) {
+	if *b == nil {
+		*b = DamageBreakdown{}
+	}
+	for src, t := range o {
+		sum := (*b)[src]
+		sum.Damage += t.Damage
+		sum.Absorbed += t.Absorbed
+		sum.Healing += t.Healing
+		sum.Overheal += t.Overheal
+		(*b)[src] = sum
+	}
+}
+
+// average divides every total by n fights, rounded
+func (b DamageBreakdown) average(n int) DamageBreakdown {
+	avg := func(v int) int { return int(math.Round(float64(v) / float64(n))) }
+	out := DamageBreakdown{}
+	for src, t := range b {
+		out[src] = SourceTotals{Damage: avg(t.Damage), Absorbed: avg(t.Absorbed), Healing: avg(t.Healing), Overheal: avg(t.Overheal)}
+	}
+	return out
+}
+
+// breakdownSource is the breakdown key for a cause and the effect behind it
+func breakdownSource(cause string, effectID int) string {
+	if effectID > 0 {
+		return "effect:" + strconv.Itoa(effectID)
+	}
+	return cause
+}
+
+// state is c's entry for a timeline point
+func (c *combatant) state() FighterState {
+	s := FighterState{ID: c.CharacterID, HP: c.hp}
+	if s.HP < 0 {
+		s.HP = 0
+	}
+	for _, l := range c.shields {
+		s.Shield += l.points
+	}
+	for _, d := range c.dots {
+		switch d.kind {
+		case "poison":
+			s.Poison += d.damage
+		case "burn":
+			s.Burn += d.damage
+		default:
+			s.Bleed += d.damage
+		}
+	}
+	return s
+}
+
+// hpOf returns the HP of the fighter with the given ID at this point (0 if absent)
+func (p TimelinePoint) hpOf(id int) int {
+	for _, f := range p.Fighters {
+		if f.ID == id {
+			return f.HP
+		}
+	}
+	return 0
+}
+
+// chartObserver collects a fight's timeline and every fighter's breakdown.
+// Self-inflicted damage isn't credited to anyone.
+type chartObserver struct {
+	NopObserver
+	timeline   []TimelinePoint
+	breakdowns map[int]DamageBreakdown
+}
+
+func newChartObserver() *chartObserver {
+	return &chartObserver{timeline: []TimelinePoint{}, breakdowns: map[int]DamageBreakdown{}}
+}
+
+func (o *chartObserver) ReadsLog() bool       { return false }
+func (o *chartObserver) ReadsSnapshots() bool { return true }
+
+func (o *chartObserver) Snapshot(point TimelinePoint) {
+	o.timeline = append(o.timeline, point)
+}
+
+func (o *chartObserver) DamageApplied(ev DamageEvent) {
+	if ev.SourceID == 0 || ev.SourceID == ev.TargetID || (ev.Amount <= 0 && ev.Absorbed <= 0) {
+		return
+	}
+	b := o.breakdownFor(ev.SourceID)
+	src := breakdownSource(ev.Cause, ev.EffectID)
+	t := b[src]
+	t.Damage += ev.Amount
+	t.Absorbed += ev.Absorbed
+	b[src] = t
+}
+
+func (o *chartObserver) Heal(ev HealEvent) {
+	if ev.Amount <= 0 && ev.Overheal <= 0 {
+		return
+	}
+	b := o.breakdownFor(ev.SourceID)
+	src := breakdownSource(ev.Cause, ev.EffectID)
+	t := b[src]
+	t.Healing += ev.Amount
+	t.Overheal += ev.Overheal
+	b[src] = t
+}
+
+// breakdownFor returns a fighter's breakdown, empty if it dealt and healed nothing
+func (o *chartObserver) breakdownFor(id int) DamageBreakdown {
+	b := o.breakdowns[id]
+	if b == nil {
+		b = DamageBreakdown{}
+		o.breakdowns[id] = b
+	}
+	return b
+}
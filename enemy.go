@@ -630,6 +630,25 @@ func getAllEnemies() ([]GameEnemy, error) {
 	return enemies, nil
 }
 
+// getGameEnemy retrieves one enemy with its talents (sql.ErrNoRows if missing)
+func getGameEnemy(enemyID int) (*GameEnemy, error) {
+	var e GameEnemy
+	err := db.QueryRow(`
+		SELECT enemy_id, enemy_name, strength, stamina, agility, luck, armor,
+		       min_damage, max_damage, asset_id, description, COALESCE(version, 1)
+		FROM game.enemies
+		WHERE enemy_id = $1
+	`, enemyID).Scan(&e.EnemyID, &e.EnemyName, &e.Strength, &e.Stamina, &e.Agility,
+		&e.Luck, &e.Armor, &e.MinDamage, &e.MaxDamage, &e.AssetID, &e.Description, &e.Version)
+	if err != nil {
+		return nil, err
+	}
+	if e.Talents, err = getEnemyTalents(enemyID); err != nil {
+		return nil, fmt.Errorf("error getting talents for enemy %d: %v", enemyID, err)
+	}
+	return &e, nil
+}
+
 // getEnemyTalents retrieves talents for a specific enemy
 func getEnemyTalents(enemyID int) ([]EnemyTalent, error) {
 	query := `
@@ -699,6 +718,26 @@ func getPendingEnemies() ([]PendingEnemy, error) {
 	return enemies, nil
 }
 
+// getPendingEnemy retrieves one pending enemy with its talents (sql.ErrNoRows if missing)
+func getPendingEnemy(toolingID int) (*PendingEnemy, error) {
+	var e PendingEnemy
+	err := db.QueryRow(`
+		SELECT tooling_id, game_id, action, approved, enemy_name, strength, stamina,
+		       agility, luck, armor, min_damage, max_damage, asset_id, description
+		FROM tooling.enemies
+		WHERE tooling_id = $1
+	`, toolingID).Scan(&e.ToolingID, &e.GameID, &e.Action, &e.Approved, &e.EnemyName,
+		&e.Strength, &e.Stamina, &e.Agility, &e.Luck, &e.Armor, &e.MinDamage,
+		&e.MaxDamage, &e.AssetID, &e.Description)
+	if err != nil {
+		return nil, err
+	}
+	if e.Talents, err = getPendingEnemyTalents(toolingID); err != nil {
+		return nil, fmt.Errorf("error getting pending talents for enemy %d: %v", toolingID, err)
+	}
+	return &e, nil
+}
+
 // getPendingEnemyTalents retrieves pending talents for a specific pending enemy
 func getPendingEnemyTalents(enemyToolingID int) ([]EnemyTalent, error) {
 	query := `
@@ -2,6 +2,7 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 )
@@ -35,18 +36,28 @@ var _ = sql.ErrNoRows
 
 // Effect represents the database structure for effects (game.effects)
 type Effect struct {
-	ID             int     `json:"id" db:"effect_id"`
-	Name           string  `json:"name" db:"name"`
-	Slot           *string `json:"slot" db:"slot"`
-	Factor         int     `json:"factor" db:"factor"`
-	Description    string  `json:"description" db:"description"`
-	CoreEffectCode *string `json:"coreEffectCode,omitempty"`
-	TriggerType    *string `json:"triggerType,omitempty"`
-	FactorType     *string `json:"factorType,omitempty"`
-	TargetSelf     *bool   `json:"targetSelf,omitempty"`
-	ConditionType  *string `json:"conditionType,omitempty"`
-	ConditionValue *int    `json:"conditionValue,omitempty"`
-	Duration       *int    `json:"duration,omitempty"`
+	ID             int              `json:"id" db:"effect_id"`
+	Name           string           `json:"name" db:"name"`
+	Slot           *string          `json:"slot" db:"slot"`
+	Factor         int              `json:"factor" db:"factor"`
+	Description    string           `json:"description" db:"description"`
+	CoreEffectCode *string          `json:"coreEffectCode,omitempty"`
+	TriggerType    *string          `json:"triggerType,omitempty"`
+	FactorType     *string          `json:"factorType,omitempty"`
+	TargetSelf     *bool            `json:"targetSelf,omitempty"`
+	ConditionType  *string          `json:"conditionType,omitempty"`
+	ConditionValue *int             `json:"conditionValue,omitempty"`
+	Duration       *int             `json:"duration,omitempty"`
+	DotStacking    *string          `json:"dotStacking,omitempty"`
+	DotMaxStacks   *int             `json:"dotMaxStacks,omitempty"`
+	DotDecay       *int             `json:"dotDecay,omitempty"`
+	DotIgnoreArmor *bool            `json:"dotIgnoreArmor,omitempty"`
+	AoE            *bool            `json:"aoe,omitempty"`
+	Condition      *EffectCondition `json:"condition,omitempty"` // structured condition (game.effects.condition JSONB)
+	Cooldown       *int             `json:"cooldown,omitempty"`
+	MaxProcs       *int             `json:"maxProcs,omitempty"`
+	ProcChance     *int             `json:"procChance,omitempty"`
+	RemoveOrder    *string          `json:"removeOrder,omitempty"`
 }
 
 // getNextAssetID returns the next available assetID from the database
@@ -80,7 +91,9 @@ func getAllEffects() ([]Effect, error) {
 
 	query := `SELECT e.effect_id, e.name, e.slot, e.factor, e.description,
 		ce.code, e.trigger_type, e.factor_type, e.target_self,
-		e.condition_type, e.condition_value, e.duration
+		e.condition_type, e.condition_value, e.duration,
+		e.dot_stacking, e.dot_max_stacks, e.dot_decay, e.dot_ignore_armor, e.aoe,
+		e.condition, e.cooldown, e.max_procs, e.proc_chance, e.remove_order
 		FROM game.effects e
 		LEFT JOIN game.core_effects ce ON e.core_effect_id = ce.core_effect_id
 		ORDER BY e.effect_id`
@@ -94,13 +107,21 @@ func getAllEffects() ([]Effect, error) {
 	for rows.Next() {
 		var effect Effect
 		var slot *string
+		var condition []byte
 
 		err := rows.Scan(&effect.ID, &effect.Name, &slot, &effect.Factor, &effect.Description,
 			&effect.CoreEffectCode, &effect.TriggerType, &effect.FactorType, &effect.TargetSelf,
-			&effect.ConditionType, &effect.ConditionValue, &effect.Duration)
+			&effect.ConditionType, &effect.ConditionValue, &effect.Duration,
+			&effect.DotStacking, &effect.DotMaxStacks, &effect.DotDecay, &effect.DotIgnoreArmor, &effect.AoE,
+			&condition, &effect.Cooldown, &effect.MaxProcs, &effect.ProcChance, &effect.RemoveOrder)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning effect row: %v", err)
 		}
+		if len(condition) > 0 {
+			if err := json.Unmarshal(condition, &effect.Condition); err != nil {
+				log.Printf("WARNING: effect %d has an unreadable condition: %v", effect.ID, err)
+			}
+		}
 
 		// Handle nullable slot
 		effect.Slot = slot
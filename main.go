@@ -274,6 +274,13 @@ func main() {
 
 	// Combat tester endpoint
 	http.HandleFunc("/api/testCombat", apiHandler(handleTestCombat))
+	http.HandleFunc("/api/replayCombat", apiHandler(handleReplayCombat))
+	http.HandleFunc("/api/testPartyCombat", apiHandler(handleTestPartyCombat))
+	http.HandleFunc("/api/simulateCombat", apiHandler(handleSimulateCombat))
+	http.HandleFunc("/api/getEffectSupportReport", apiHandler(handleGetEffectSupportReport))
+	http.HandleFunc("/api/getCombatRules", apiHandler(handleGetCombatRules))
+	http.HandleFunc("/api/saveCombatRules", apiHandler(handleSaveCombatRules))
+	http.HandleFunc("/api/deleteCombatRules", apiHandler(handleDeleteCombatRules))
 
 	// Bulk combat (effect ranking) endpoints
 	http.HandleFunc("/api/startBulkCombat", apiHandler(handleStartBulkCombat))
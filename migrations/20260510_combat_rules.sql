@@ -0,0 +1,23 @@
+-- Combat rules profiles: named sets of engine balance constants (max turns,
+-- HP per stamina, crit multiplier, dodge/crit curve, armor constant) that the
+-- combat tester, bulk combat and builds runs can select by profile_id.
+
+CREATE SCHEMA IF NOT EXISTS tooling;
+
+CREATE TABLE IF NOT EXISTS tooling.combat_rules (
+    profile_id  BIGSERIAL PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE,
+    description TEXT,
+    rules       JSONB NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The constants the engine used before profiles existed
+INSERT INTO tooling.combat_rules (name, description, rules)
+VALUES (
+    'default',
+    'Live game rules',
+    '{"maxTurns":30,"hpPerStamina":10,"critMultiplier":200,"chanceScale":10,"chanceMin":1,"chanceMax":50,"armorConstant":100}'
+)
+ON CONFLICT (name) DO NOTHING;
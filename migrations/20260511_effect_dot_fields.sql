@@ -0,0 +1,10 @@
+-- Damage-over-time settings for bleed, poison and burn effects. NULL keeps the
+-- engine defaults: every application stacks, no decay, and only burn is
+-- mitigated by armor. game.effects.duration is the number of ticks.
+
+ALTER TABLE game.effects
+    ADD COLUMN IF NOT EXISTS dot_stacking     TEXT
+        CHECK (dot_stacking IN ('stack', 'refresh', 'max_stacks')),
+    ADD COLUMN IF NOT EXISTS dot_max_stacks   INTEGER CHECK (dot_max_stacks > 0),
+    ADD COLUMN IF NOT EXISTS dot_decay        INTEGER CHECK (dot_decay >= 0),
+    ADD COLUMN IF NOT EXISTS dot_ignore_armor BOOLEAN;
@@ -0,0 +1,5 @@
+-- AoE effects land on every living member of the opposing team (or of the
+-- owner's team when target_self is set) in party combat. NULL = single target.
+
+ALTER TABLE game.effects
+    ADD COLUMN IF NOT EXISTS aoe BOOLEAN;
@@ -0,0 +1,12 @@
+-- Structured effect conditions: a JSON tree of and/or nodes over leaf checks
+-- (hp_below_percent, hp_above_percent, bleeding, stunned, turn_at_least,
+-- first_attack, after_dodged), each optionally aimed at the target, self or
+-- opponent. NULL = no condition; condition_type/condition_value still apply.
+--
+-- Example: {"type":"and","conditions":[
+--   {"type":"hp_below_percent","subject":"opponent","value":30},
+--   {"type":"bleeding"}]}
+
+ALTER TABLE game.effects
+    ADD COLUMN IF NOT EXISTS condition JSONB
+        CHECK (condition IS NULL OR jsonb_typeof(condition) = 'object');
@@ -0,0 +1,8 @@
+-- Proc limits for triggered effects: cooldown in turns between procs, a cap on
+-- procs per fight and an internal proc chance (percent) rolled before the
+-- effect applies. NULL = no limit / always procs.
+
+ALTER TABLE game.effects
+    ADD COLUMN IF NOT EXISTS cooldown    INTEGER CHECK (cooldown >= 0),
+    ADD COLUMN IF NOT EXISTS max_procs   INTEGER CHECK (max_procs >= 0),
+    ADD COLUMN IF NOT EXISTS proc_chance INTEGER CHECK (proc_chance BETWEEN 0 AND 100);
@@ -0,0 +1,5 @@
+-- Removal order for cleanse/dispel effects: which temp buffs and DoT stacks
+-- go first when the effect can't remove them all. NULL = newest.
+
+ALTER TABLE game.effects
+    ADD COLUMN IF NOT EXISTS remove_order TEXT CHECK (remove_order IN ('newest', 'strongest'));
@@ -0,0 +1,6 @@
+-- Fight metrics per build and milestone: average time to kill and damage by
+-- source, collected by the tournament's metrics observer. NULL for builds
+-- added to a run after it finished.
+
+ALTER TABLE tooling.build_results
+    ADD COLUMN IF NOT EXISTS metrics JSONB;
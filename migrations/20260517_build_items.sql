@@ -0,0 +1,10 @@
+-- Equipment per build: one game.items row per slot (the item's type), with
+-- the gem in its socket if it has one. Items aren't scaled by milestone day.
+
+CREATE TABLE IF NOT EXISTS tooling.build_items (
+    build_id BIGINT NOT NULL REFERENCES tooling.builds(build_id) ON DELETE CASCADE,
+    slot     TEXT NOT NULL,
+    item_id  INT NOT NULL,
+    gem_id   INT,
+    PRIMARY KEY (build_id, slot)
+);
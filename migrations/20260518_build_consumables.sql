@@ -0,0 +1,17 @@
+-- Blessings (perks with is_blessing) and potions per build. Potions are
+-- drunk once, at the start of the build's first turn below hp_below_percent
+-- (0 = the first turn).
+
+CREATE TABLE IF NOT EXISTS tooling.build_blessings (
+    build_id BIGINT NOT NULL REFERENCES tooling.builds(build_id) ON DELETE CASCADE,
+    perk_id  INT NOT NULL,
+    PRIMARY KEY (build_id, perk_id)
+);
+
+CREATE TABLE IF NOT EXISTS tooling.build_potions (
+    build_id         BIGINT NOT NULL REFERENCES tooling.builds(build_id) ON DELETE CASCADE,
+    potion_order     INT NOT NULL,
+    item_id          INT NOT NULL,
+    hp_below_percent INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (build_id, potion_order)
+);
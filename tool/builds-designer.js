@@ -34,6 +34,7 @@ function initBuilds() {
     document.getElementById('buildsSaveBtn').addEventListener('click', saveCurrentBuild);
     document.getElementById('buildsDeleteBtn').addEventListener('click', deleteCurrentBuild);
     document.getElementById('buildsStartRunBtn').addEventListener('click', startBuildRun);
+    fillCombatRulesSelect(document.getElementById('buildsRulesProfile'));
 
     // Activate when Test2 tab opens.
     document.querySelectorAll('.combat-sidebar-btn').forEach(btn => {
@@ -59,6 +60,8 @@ async function refreshBuildsData() {
             await loadEnemiesData();
         }
         buildsState.perks = typeof getPerks === 'function' ? getPerks() : [];
+        if (typeof loadItemsData === 'function') await loadItemsData();
+        if (typeof renderLoadoutPicker === 'function') renderLoadoutPicker(document.getElementById('buildEquipment'));
     } catch (e) {
         console.error('Builds: data load error', e);
     }
@@ -120,6 +123,7 @@ function startNewBuild() {
     document.getElementById('buildArm').value = 10;
     document.getElementById('buildMinDmg').value = 5;
     document.getElementById('buildMaxDmg').value = 10;
+    if (typeof setLoadoutPicker === 'function') setLoadoutPicker(document.getElementById('buildEquipment'), null);
     showEditor();
     renderTalentTree();
     renderBuildsList();
@@ -146,6 +150,7 @@ async function selectBuild(id) {
         document.getElementById('buildArm').value = b.armor;
         document.getElementById('buildMinDmg').value = b.minDamage;
         document.getElementById('buildMaxDmg').value = b.maxDamage;
+        if (typeof setLoadoutPicker === 'function') setLoadoutPicker(document.getElementById('buildEquipment'), b);
 
         buildsState.talents = new Map();
         buildsState.talentOrderSeq = 0;
@@ -202,6 +207,7 @@ async function saveCurrentBuild() {
         minDamage: parseInt(document.getElementById('buildMinDmg').value) || 0,
         maxDamage: parseInt(document.getElementById('buildMaxDmg').value) || 0,
         talents,
+        ...(typeof readLoadoutPicker === 'function' ? readLoadoutPicker(document.getElementById('buildEquipment')) : {}),
     };
 
     try {
@@ -495,7 +501,11 @@ async function startBuildRun() {
         const resp = await fetch('/api/startBuildRun', {
             method: 'POST',
             headers: { 'Authorization': `Bearer ${token}`, 'Content-Type': 'application/json' },
-            body: JSON.stringify({ rounds, fightsPerPair }),
+            body: JSON.stringify({
+                rounds,
+                fightsPerPair,
+                rulesProfileId: getSelectedCombatRulesId(document.getElementById('buildsRulesProfile')),
+            }),
         });
         if (!resp.ok) {
             alert('Failed: ' + (await resp.text()));
@@ -663,7 +673,7 @@ function renderRankingsForMilestone(run) {
         return `
             <tr>
                 <td class="builds-rank">${r.rank || '-'}</td>
-                <td class="builds-name">${escBHtml(r.buildName || ('#' + r.buildId))}</td>
+                <td class="builds-name" title="${escBHtml(formatBuildMetrics(r.metrics))}">${escBHtml(r.buildName || ('#' + r.buildId))}</td>
                 <td class="builds-rating">${Math.round(r.rating)}</td>
                 <td>${r.wins}</td>
                 <td>${r.losses}</td>
@@ -678,6 +688,17 @@ function renderRankingsForMilestone(run) {
 // Helpers
 // ──────────────────────────────────────────────────────────────────
 
+// formatBuildMetrics renders time to kill and damage by source for a tooltip
+function formatBuildMetrics(m) {
+    if (!m) return '';
+    const parts = [];
+    if (m.avgTimeToKill) parts.push(`TTK ${m.avgTimeToKill.toFixed(1)} turns`);
+    Object.entries(m.damageBySource || {})
+        .sort((a, b) => b[1] - a[1])
+        .forEach(([cause, dmg]) => parts.push(`${cause} ${dmg.toFixed(0)}`));
+    return parts.join(' · ');
+}
+
 function escBHtml(s) {
     return String(s == null ? '' : s).replace(/[&<>"']/g, c => ({
         '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'
@@ -29,6 +29,7 @@ if (document.readyState === 'loading') {
 
 function initBulkCombat() {
     document.getElementById('bulkStartBtn').addEventListener('click', startBulkRun);
+    fillCombatRulesSelect(document.getElementById('bulkRulesProfile'));
 
     document.querySelectorAll('.combat-sidebar-btn').forEach(btn => {
         btn.addEventListener('click', () => {
@@ -62,6 +63,7 @@ async function startBulkRun() {
         valueMin:      parseFloat(document.getElementById('bulkValueMin').value)      || 1,
         valueMax:      parseFloat(document.getElementById('bulkValueMax').value)      || 50,
         effectIds:     [],
+        rulesProfileId: getSelectedCombatRulesId(document.getElementById('bulkRulesProfile')),
     };
 
     setBulkStatus('Starting calibration…');
@@ -184,6 +186,8 @@ function renderBulkResults(run) {
         const trace = (r.phaseHistory || [])
             .map(p => p.value.toFixed(1))
             .join(' → ') || '-';
+        const lastPhase = (r.phaseHistory || []).slice(-1)[0];
+        const metrics = formatBulkMetrics(lastPhase?.metrics);
         return `
             <tr>
                 <td class="bulk-rank">${rank}</td>
@@ -193,13 +197,24 @@ function renderBulkResults(run) {
                 <td>${r.wins}</td>
                 <td>${r.losses}</td>
                 <td>${winPct}${winPct === '-' ? '' : '%'}</td>
-                <td class="bulk-trace" title="${trace}">${trace}</td>
+                <td class="bulk-trace" title="${trace}${metrics ? '\n' + metrics : ''}">${trace}</td>
             </tr>
         `;
     }).join('');
     tbody.innerHTML = rows;
 }
 
+// formatBulkMetrics renders a phase's time to kill and damage by source for a tooltip
+function formatBulkMetrics(m) {
+    if (!m) return '';
+    const parts = [];
+    if (m.avgTimeToKill) parts.push(`TTK ${m.avgTimeToKill.toFixed(1)} turns`);
+    Object.entries(m.damageBySource || {})
+        .sort((a, b) => b[1] - a[1])
+        .forEach(([cause, dmg]) => parts.push(`${cause} ${dmg.toFixed(0)}`));
+    return parts.join(' · ');
+}
+
 // ── History ─────────────────────────────────────────────
 
 async function loadBulkHistory() {
@@ -6,10 +6,24 @@ const ACTION_ICONS = {
     dodge:          '💨',
     stun:           '😵',
     stunned:        '😵',
+    stun_resisted:  '🧠',
+    stun_immune:    '🧠',
+    stun_immunity:  '🧠',
     bleed:          '🩸',
+    poison:         '🧪',
+    burn:           '🔥',
     counterattack:  '🔄',
     double_attack:  '⚡',
     heal:           '💚',
+    initiative:     '⏱️',
+    shield:         '🛡️',
+    lifesteal:      '🧛',
+    reflect_damage: '🪞',
+    armor_penetration: '🗡️',
+    shield_break:   '💔',
+    cleanse:        '✨',
+    dispel:         '🚫',
+    buff_removed:   '🧹',
 };
 
 // ── State ────────────────────────────────────────────
@@ -21,6 +35,7 @@ let combatTalentOrder2 = 0;
 let combatAnimator = null;
 let combatResult = null;
 let combatPerks = [];
+let combatBuilds = [];
 
 // ── Init ─────────────────────────────────────────────
 
@@ -55,6 +70,9 @@ function initCombatTester() {
     });
 
     document.getElementById('combatFightBtn').addEventListener('click', runCombat);
+    const rulesSelect = document.getElementById('combatRulesProfile');
+    fillCombatRulesSelect(rulesSelect);
+    rulesSelect.addEventListener('change', () => { updateHpCalc(1); updateHpCalc(2); });
 
     // HP calc on stamina change
     for (const n of [1, 2]) {
@@ -99,9 +117,15 @@ async function loadCombatData() {
         if (typeof loadEnemiesData === 'function' && (!GlobalData.talents || GlobalData.talents.length === 0)) {
             await loadEnemiesData();
         }
+        if (typeof loadItemsData === 'function') await loadItemsData();
         combatPerks = typeof getPerks === 'function' ? getPerks() : [];
+        combatBuilds = await fetchCombatBuilds();
         buildCombatTalentTree(1);
         buildCombatTalentTree(2);
+        fillCombatSourceSelect(1);
+        fillCombatSourceSelect(2);
+        renderLoadoutPicker(document.getElementById('combatEquipment1'));
+        renderLoadoutPicker(document.getElementById('combatEquipment2'));
     } catch (e) {
         console.error('Combat data load error:', e);
     }
@@ -111,7 +135,9 @@ async function loadCombatData() {
 
 function updateHpCalc(panel) {
     const sta = parseInt(document.getElementById(`combatSta${panel}`).value) || 0;
-    document.getElementById(`combatHpCalc${panel}`).textContent = sta * 10;
+    const rulesId = getSelectedCombatRulesId(document.getElementById('combatRulesProfile'));
+    const profile = GlobalData.combatRules.find(p => p.profileId === rulesId);
+    document.getElementById(`combatHpCalc${panel}`).textContent = sta * (profile?.rules.hpPerStamina || 10);
 }
 
 function checkFightReady() {
@@ -541,36 +567,17 @@ function updateCombatTalentCell(panel, talentId) {
     if (perkInd) perkInd.classList.toggle('assigned', !!current.perkId);
 }
 
-// ── Resolve talents → effects ────────────────────────
+// ── Talent picks ─────────────────────────────────
 
-function resolveTalentEffects(panel) {
+// The server resolves the picks into effects, perks included (see talentEffects in builds.go)
+function combatTalentPicks(panel) {
     const map = panel === 1 ? combatTalents1 : combatTalents2;
-    const effects = [];
-    let id = 1;
-    const talents = typeof getTalents === 'function' ? getTalents() : [];
-    const allEffects = typeof getEffects === 'function' ? getEffects() : (GlobalData?.effects || []);
-
+    const picks = [];
     map.forEach((data, talentId) => {
         if (data.points <= 0) return;
-        const talent = talents.find(t => t.talentId === talentId);
-        if (!talent || !talent.effectId) return;
-
-        const effect = allEffects.find(e => e.id === talent.effectId);
-        if (!effect || !effect.coreEffectCode) return;
-
-        effects.push({
-            effectId: id++,
-            coreEffectCode: effect.coreEffectCode,
-            triggerType: effect.triggerType || 'passive',
-            factorType: effect.factorType || 'percent',
-            targetSelf: effect.targetSelf || false,
-            conditionType: effect.conditionType || null,
-            conditionValue: effect.conditionValue || null,
-            duration: effect.duration || null,
-            value: Math.round((talent.factor || 0) * data.points),
-        });
+        picks.push({ talentId, points: data.points, talentOrder: data.talentOrder, perkId: data.perkId || undefined });
     });
-    return effects;
+    return picks;
 }
 
 // ── Overlay ──────────────────────────────────────────
@@ -631,6 +638,9 @@ async function runCombat() {
         combatant1: buildCombatant(1),
         combatant2: buildCombatant(2),
     };
+    const seedValue = document.getElementById('combatSeed').value.trim();
+    if (seedValue !== '') payload.seed = parseInt(seedValue);
+    payload.rulesProfileId = getSelectedCombatRulesId(document.getElementById('combatRulesProfile'));
 
     try {
         const token = await getCurrentAccessToken();
@@ -667,8 +677,125 @@ async function runCombat() {
     }
 }
 
+// tooling.builds rows the source select offers
+async function fetchCombatBuilds() {
+    const token = await getCurrentAccessToken();
+    if (!token) return [];
+    const resp = await fetch('/api/getBuilds', { headers: { 'Authorization': `Bearer ${token}` } });
+    if (!resp.ok) return [];
+    const data = await resp.json();
+    return data.success ? data.builds || [] : [];
+}
+
+// Stored enemies and builds the server can assemble itself (talents, perks
+// and all); a build also takes the day of its career it fights on
+function fillCombatSourceSelect(panel) {
+    const select = document.getElementById(`combatSource${panel}`);
+    if (!select) return;
+    const opt = (value, label) => `<option value="${value}">${_ctEsc(label)}</option>`;
+    const enemies = GlobalData.enemies || [];
+    const pending = GlobalData.pendingEnemies || [];
+    select.innerHTML = opt('', 'Custom')
+        + (enemies.length ? `<optgroup label="Enemies">${enemies.map(e => opt(`enemy:${e.enemyId}`, e.enemyName)).join('')}</optgroup>` : '')
+        + (pending.length ? `<optgroup label="Pending enemies">${pending.map(e => opt(`pending:${e.toolingId}`, e.enemyName)).join('')}</optgroup>` : '')
+        + (combatBuilds.length ? `<optgroup label="Builds">${combatBuilds.map(b => opt(`build:${b.buildId}`, b.buildName)).join('')}</optgroup>` : '');
+    const day = document.getElementById(`combatBuildDay${panel}`);
+    const syncDay = () => { if (day) day.hidden = !select.value.startsWith('build:'); };
+    select.onchange = syncDay;
+    syncDay();
+}
+
+// Worn item slots, matching equipmentSlots in combat_equipment.go
+const COMBAT_EQUIPMENT_SLOTS = ['weapon', 'head', 'chest', 'hands', 'feet', 'belt', 'legs', 'back', 'amulet'];
+
+// Blessing and potion slots the picker offers; the server allows 3 blessings
+const COMBAT_BLESSING_SLOTS = 3;
+const COMBAT_POTION_SLOTS = 2;
+
+// Loadout picker: one select per equipment slot, plus a gem select once a
+// socketed item is picked, then blessings and potions (with the HP % they're
+// drunk below). Shared with the builds designer.
+function renderLoadoutPicker(container) {
+    if (!container) return;
+    const items = GlobalData.items || [];
+    const opt = (value, label) => `<option value="${value}">${_ctEsc(label)}</option>`;
+    const options = list => list.map(i => opt(i.id, i.name)).join('');
+    const gems = options(items.filter(i => i.type === 'gem'));
+    const potions = options(items.filter(i => i.type === 'potion' && i.effectID));
+    const blessings = options((typeof getPerks === 'function' ? getPerks() : []).filter(p => p.is_blessing));
+    const slot = (label, inner) => `<div class="combat-equipment-slot"><label>${label}</label>${inner}</div>`;
+    container.innerHTML = COMBAT_EQUIPMENT_SLOTS.map(s => slot(s,
+        `<select data-slot="${s}">${opt('', '—')}${options(items.filter(i => i.type === s))}</select>
+         <select data-gem-for="${s}" hidden>${opt('', 'No gem')}${gems}</select>`)).join('')
+        + Array.from({ length: COMBAT_BLESSING_SLOTS }, (_, i) => slot(`Blessing ${i + 1}`,
+            `<select data-blessing>${opt('', '—')}${blessings}</select>`)).join('')
+        + Array.from({ length: COMBAT_POTION_SLOTS }, (_, i) => slot(`Potion ${i + 1}`,
+            `<select data-potion>${opt('', '—')}${potions}</select>
+             <input type="number" data-potion-hp min="0" max="100" placeholder="Below HP % (first turn)">`)).join('');
+    container.querySelectorAll('select[data-slot]').forEach(select => {
+        select.addEventListener('change', () => syncEquipmentGem(container, select));
+    });
+}
+
+function syncEquipmentGem(container, select) {
+    const gem = container.querySelector(`select[data-gem-for="${select.dataset.slot}"]`);
+    gem.hidden = !getItemById(select.value)?.socket;
+    if (gem.hidden) gem.value = '';
+}
+
+// readLoadoutPicker returns { equipment, blessings, potions } as the server takes them
+function readLoadoutPicker(container) {
+    const loadout = { equipment: [], blessings: [], potions: [] };
+    if (!container) return loadout;
+    container.querySelectorAll('select[data-slot]').forEach(select => {
+        if (!select.value) return;
+        const gem = container.querySelector(`select[data-gem-for="${select.dataset.slot}"]`).value;
+        loadout.equipment.push(gem ? { itemId: parseInt(select.value), gemId: parseInt(gem) } : { itemId: parseInt(select.value) });
+    });
+    container.querySelectorAll('select[data-blessing]').forEach(select => {
+        if (select.value) loadout.blessings.push(parseInt(select.value));
+    });
+    container.querySelectorAll('select[data-potion]').forEach(select => {
+        if (!select.value) return;
+        const hp = parseInt(select.nextElementSibling.value) || 0;
+        loadout.potions.push({ itemId: parseInt(select.value), hpBelowPercent: hp });
+    });
+    return loadout;
+}
+
+function setLoadoutPicker(container, loadout) {
+    if (!container) return;
+    container.querySelectorAll('select, input').forEach(el => { el.value = ''; });
+    container.querySelectorAll('select[data-slot]').forEach(select => syncEquipmentGem(container, select));
+    (loadout?.equipment || []).forEach(eq => {
+        const item = getItemById(eq.itemId);
+        const select = item && container.querySelector(`select[data-slot="${item.type}"]`);
+        if (!select) return;
+        select.value = eq.itemId;
+        syncEquipmentGem(container, select);
+        if (eq.gemId) container.querySelector(`select[data-gem-for="${item.type}"]`).value = eq.gemId;
+    });
+    const blessingSelects = container.querySelectorAll('select[data-blessing]');
+    (loadout?.blessings || []).forEach((id, i) => { if (blessingSelects[i]) blessingSelects[i].value = id; });
+    const potionSelects = container.querySelectorAll('select[data-potion]');
+    (loadout?.potions || []).forEach((p, i) => {
+        if (!potionSelects[i]) return;
+        potionSelects[i].value = p.itemId;
+        potionSelects[i].nextElementSibling.value = p.hpBelowPercent || '';
+    });
+}
+
 function buildCombatant(panel) {
+    const source = document.getElementById(`combatSource${panel}`)?.value || '';
+    const loadout = readLoadoutPicker(document.getElementById(`combatEquipment${panel}`));
+    if (source.startsWith('enemy:')) return { enemyId: parseInt(source.slice(6)), ...loadout };
+    if (source.startsWith('pending:')) return { pendingEnemyId: parseInt(source.slice(8)), ...loadout };
+    if (source.startsWith('build:')) {
+        const day = parseInt(document.getElementById(`combatBuildDay${panel}`)?.value) || 0;
+        return { buildId: parseInt(source.slice(6)), buildDay: day, ...loadout };
+    }
     return {
+        ...loadout,
         name: document.getElementById(`combatName${panel}`).value || `Combatant ${panel}`,
         strength:  parseInt(document.getElementById(`combatStr${panel}`).value) || 0,
         stamina:   parseInt(document.getElementById(`combatSta${panel}`).value) || 1,
@@ -677,7 +804,8 @@ function buildCombatant(panel) {
         armor:     parseInt(document.getElementById(`combatArm${panel}`).value) || 0,
         minDamage: parseInt(document.getElementById(`combatMinDmg${panel}`).value) || 0,
         maxDamage: parseInt(document.getElementById(`combatMaxDmg${panel}`).value) || 0,
-        effects: resolveTalentEffects(panel),
+        effects: [],
+        talents: combatTalentPicks(panel),
     };
 }
 
@@ -812,7 +940,14 @@ class CombatAnimator {
                 await this.animateStun(isC1, false);
                 break;
             case 'bleed':
-                await this.animateBleed(isC1, entry.factor);
+            case 'poison':
+            case 'burn':
+                if (entry.triggerType) {
+                    this.showAction(`${ACTION_ICONS[entry.action]} ${entry.action} applied`);
+                    await sleep(250);
+                } else {
+                    await this.animateBleed(isC1, entry.factor);
+                }
                 break;
             case 'counterattack':
                 await this.animateCounterattack(isC1, entry.factor);
@@ -820,6 +955,8 @@ class CombatAnimator {
             case 'heal':
                 await this.animateHeal(isC1, entry.factor);
                 break;
+            case 'initiative':
+                break;
             default:
                 this.showAction(`${ACTION_ICONS[entry.action] || '⚔️'} ${entry.action}`);
                 await sleep(250);
@@ -832,14 +969,23 @@ class CombatAnimator {
 
     getHpDelta(entry) {
         const isC1 = entry.characterId === this.c1.id;
-        const f = entry.factor || 0;
+        const f = (entry.factor || 0) - (entry.absorbed || 0);
         switch (entry.action) {
             case 'attack': case 'crit': case 'double_attack': case 'counterattack':
                 return isC1 ? { c1: 0, c2: -f } : { c1: -f, c2: 0 };
-            case 'bleed':
+            case 'bleed': case 'poison': case 'burn':
+                // Application entries (with a trigger) belong to the caster; only ticks cost HP
+                if (entry.triggerType) return { c1: 0, c2: 0 };
                 return isC1 ? { c1: -f, c2: 0 } : { c1: 0, c2: -f };
-            case 'heal':
-                return isC1 ? { c1: f, c2: 0 } : { c1: 0, c2: f };
+            case 'reflect_damage':
+                return isC1 ? { c1: 0, c2: -f } : { c1: -f, c2: 0 };
+            case 'lifesteal':
+                return isC1 ? { c1: f - (entry.overheal || 0), c2: 0 } : { c1: 0, c2: f - (entry.overheal || 0) };
+            case 'heal': {
+                const healed = (entry.factor || 0) - (entry.overheal || 0);
+                const onC1 = entry.targetId ? entry.targetId === this.c1.id : isC1;
+                return onC1 ? { c1: healed, c2: 0 } : { c1: 0, c2: healed };
+            }
             default:
                 return { c1: 0, c2: 0 };
         }
@@ -1002,6 +1148,8 @@ function renderCombatLog() {
     const log = combatResult.log || [];
     const container = document.getElementById('combatLogEntries');
     container.innerHTML = '';
+    document.getElementById('combatLogSeed').textContent =
+        combatResult.header.seed !== undefined ? `Seed ${combatResult.header.seed}` : '';
 
     const _esc = typeof escapeHtml === 'function' ? escapeHtml : (t) => {
         const d = document.createElement('div'); d.textContent = t; return d.innerHTML;
@@ -1054,11 +1202,23 @@ function renderCombatStats() {
                                      v2: pct(s2.dodgedAttacks, s2.dodgedAttacks + s1.attacks) + '%' },
         { label: 'Healing Done',    v1: s1.healingDone,   v2: s2.healingDone },
         { label: '% Max HP Healed', v1: pct(s1.healingDone, c1.maxHp) + '%', v2: pct(s2.healingDone, c2.maxHp) + '%' },
+        { label: 'Overheal',        v1: s1.overheal,      v2: s2.overheal },
+        { label: 'Healing Blocked', v1: s1.healingBlocked, v2: s2.healingBlocked },
+        { label: 'Lifesteal',       v1: s1.lifestealHealing, v2: s2.lifestealHealing },
+        { label: 'Damage Reflected', v1: s1.damageReflected, v2: s2.damageReflected },
         { label: 'Stuns Applied',   v1: s1.stunApplied,   v2: s2.stunApplied },
         { label: 'Times Stunned',   v1: s1.timesStunned,  v2: s2.timesStunned },
+        { label: 'Stuns Resisted',  v1: s1.stunsResisted, v2: s2.stunsResisted },
+        { label: 'Stuns Immune',    v1: s1.stunsImmune,   v2: s2.stunsImmune },
         { label: 'Bleed Applied',   v1: s1.bleedApplied,  v2: s2.bleedApplied },
+        { label: 'Poison Applied',  v1: s1.poisonApplied, v2: s2.poisonApplied },
+        { label: 'Burn Applied',    v1: s1.burnApplied,   v2: s2.burnApplied },
         { label: 'Counter Hits',    v1: s1.counterHits,   v2: s2.counterHits },
         { label: 'Double Attacks',  v1: s1.doubleAttacks, v2: s2.doubleAttacks },
+        { label: 'Shield Absorbed', v1: s1.shieldAbsorbed, v2: s2.shieldAbsorbed },
+        { label: 'Debuffs Cleansed', v1: s1.debuffsCleansed, v2: s2.debuffsCleansed },
+        { label: 'Buffs Dispelled', v1: s1.buffsDispelled, v2: s2.buffsDispelled },
+        { label: 'Potions Used', v1: s1.potionsUsed, v2: s2.potionsUsed },
     ];
 
     section.innerHTML = `
@@ -1080,6 +1240,54 @@ function renderCombatStats() {
                     <td class="stat-val c2">${r.v2}</td>
                 </tr>`).join('')}
             </tbody>
+        </table>
+        ${renderHpTimeline(combatResult)}
+        ${renderBreakdownTable(combatResult, _esc)}`;
+}
+
+// HP (solid) and shield (dashed) of both sides after every half-turn
+function renderHpTimeline(result) {
+    const points = result.timeline || [];
+    if (points.length < 2) return '';
+    const maxHp = Math.max(result.header.combatant1.maxHp, result.header.combatant2.maxHp, 1);
+    const w = 600, h = 160, pad = 4;
+    const x = i => pad + i * (w - 2 * pad) / (points.length - 1);
+    const y = v => h - pad - Math.min(v, maxHp) * (h - 2 * pad) / maxHp;
+    const line = (side, key) => points.map((p, i) => `${x(i).toFixed(1)},${y(p.fighters[side][key]).toFixed(1)}`).join(' ');
+    return `
+        <div class="combat-stats-header"><h3>HP Timeline</h3></div>
+        <svg class="combat-hp-chart" viewBox="0 0 ${w} ${h}" preserveAspectRatio="none">
+            <polyline class="hp c1" points="${line(0, 'hp')}"/>
+            <polyline class="hp c2" points="${line(1, 'hp')}"/>
+            <polyline class="shield c1" points="${line(0, 'shield')}"/>
+            <polyline class="shield c2" points="${line(1, 'shield')}"/>
+        </svg>`;
+}
+
+// Damage and healing per source: attack, crit, counterattack, reflect, lifesteal, effect:<id>
+function renderBreakdownTable(result, esc) {
+    const b1 = (result.breakdown && result.breakdown.combatant1) || {};
+    const b2 = (result.breakdown && result.breakdown.combatant2) || {};
+    const sources = [...new Set([...Object.keys(b1), ...Object.keys(b2)])].sort();
+    if (!sources.length) return '';
+    const label = src => src.startsWith('effect:') ? `Effect #${src.slice(7)}` : src.replace(/_/g, ' ');
+    const cell = t => {
+        if (!t) return '—';
+        const parts = [];
+        if (t.damage || t.absorbed) parts.push(`${t.damage || 0} dmg${t.absorbed ? ` (+${t.absorbed} absorbed)` : ''}`);
+        if (t.healing || t.overheal) parts.push(`${t.healing || 0} heal${t.overheal ? ` (+${t.overheal} over)` : ''}`);
+        return parts.join(', ') || '—';
+    };
+    return `
+        <div class="combat-stats-header"><h3>Damage &amp; Healing by Source</h3></div>
+        <table class="combat-stats-table">
+            <tbody>
+                ${sources.map(src => `<tr>
+                    <td class="stat-val c1">${cell(b1[src])}</td>
+                    <td class="stat-label">${esc(label(src))}</td>
+                    <td class="stat-val c2">${cell(b2[src])}</td>
+                </tr>`).join('')}
+            </tbody>
         </table>`;
 }
 
@@ -1091,10 +1299,25 @@ function formatAction(action, factor, actor, opponent, esc) {
         case 'dodge':         return `attack dodged by ${e(opponent)}`;
         case 'stun':          return `stuns ${e(opponent)}`;
         case 'stunned':       return 'is stunned!';
+        case 'stun_resisted': return `stun resisted by ${e(opponent)}`;
+        case 'stun_immune':   return `${e(opponent)} is immune to stuns`;
+        case 'stun_immunity': return 'shakes off the stun';
         case 'bleed':         return factor > 0 ? `bleeds for <b>${factor}</b>` : 'applies bleed';
+        case 'poison':        return `poison deals <b>${factor}</b>`;
+        case 'burn':          return `burns for <b>${factor}</b>`;
         case 'counterattack': return `counters for <b>${factor}</b>`;
         case 'double_attack': return `double attack for <b>${factor}</b>`;
         case 'heal':          return `heals for <b>${factor}</b>`;
+        case 'initiative':    return `acts (#${factor} this turn)`;
+        case 'shield':        return `gains a <b>${factor}</b> point shield`;
+        case 'lifesteal':     return `drains <b>${factor}</b> HP`;
+        case 'reflect_damage': return `reflects <b>${factor}</b> damage onto ${e(opponent)}`;
+        case 'armor_penetration': return `pierces armor for <b>${factor}</b> extra damage`;
+        case 'shield_break':  return 'shield breaks';
+        case 'cleanse':       return `cleanses <b>${factor}</b> debuff${factor === 1 ? '' : 's'}`;
+        case 'dispel':        return `dispels <b>${factor}</b> buff${factor === 1 ? '' : 's'} from ${e(opponent)}`;
+        case 'buff_removed':  return `loses a buff (${factor})`;
+        case 'potion':        return 'drinks a potion';
         default:              return `${action} (${factor})`;
     }
 }
@@ -24,7 +24,8 @@ const GlobalData = {
     questAssets: [],       // Array of available quest assets from S3 (images/quests)
     expeditionMapAssets: [], // Array of expedition map assets from S3 (images/expedition-maps)
     cosmetics: [],         // Array of all cosmetics from game.cosmetics
-    cosmeticAssets: []     // Array of available cosmetic assets from S3
+    cosmeticAssets: [],    // Array of available cosmetic assets from S3
+    combatRules: []        // Array of combat rules profiles from tooling.combat_rules
 };
 
 const DEFAULT_ASSET_PUBLIC_BASE_URL = 'https://pub-b959ac8ae579488bb4ed33c01a618ae2.r2.dev';
@@ -392,6 +393,7 @@ let serversLoadingPromise = null;
 let recentEventsLoadingPromise = null;
 let cosmeticsLoadingPromise = null;
 let cosmeticAssetsLoadingPromise = null;
+let combatRulesLoadingPromise = null;
 
 // --- Effects ---
 async function loadEffectsData(options = {}) {
@@ -1126,6 +1128,54 @@ function getCosmeticAssets() {
     return GlobalData.cosmeticAssets;
 }
 
+// --- Combat rules profiles – shared by combat tester, bulk combat & builds ---
+async function loadCombatRulesData(options = {}) {
+    const forceReload = options?.forceReload === true;
+    if (!forceReload && GlobalData.combatRules.length > 0) {
+        return GlobalData.combatRules;
+    }
+    if (combatRulesLoadingPromise) return combatRulesLoadingPromise;
+
+    combatRulesLoadingPromise = (async () => {
+        try {
+            const data = await getAuthenticatedJson('/api/getCombatRules', {
+                expectSuccess: true,
+                headers: { 'Content-Type': 'application/json' }
+            });
+            setGlobalArray('combatRules', Array.isArray(data?.profiles) ? data.profiles : []);
+            return GlobalData.combatRules;
+        } catch (error) {
+            console.error('Error loading combat rules:', error);
+            throw error;
+        } finally {
+            combatRulesLoadingPromise = null;
+        }
+    })();
+    return combatRulesLoadingPromise;
+}
+
+// Fill a <select> with the rules profiles; the empty option means server defaults
+async function fillCombatRulesSelect(select) {
+    if (!select) return;
+    let profiles = [];
+    try {
+        profiles = await loadCombatRulesData();
+    } catch {
+        profiles = [];
+    }
+    const current = select.value;
+    select.innerHTML = '<option value="">Default rules</option>' + profiles
+        .map(p => `<option value="${p.profileId}">${String(p.name).replace(/</g, '&lt;')}</option>`)
+        .join('');
+    select.value = current;
+}
+
+// Selected profile ID from a rules <select>, or undefined for the defaults
+function getSelectedCombatRulesId(select) {
+    const value = select?.value;
+    return value ? parseInt(value, 10) : undefined;
+}
+
 // === PRELOAD REGISTRY ===
 
 const GLOBAL_DATA_LOADERS = {